@@ -0,0 +1,122 @@
+// Command import loads upstream JMdict XML into the entries collection
+//
+// Entries are upserted by sequence number, so running it again against a
+// newer JMdict release refreshes the collection in place.
+//
+//	go run ./cmd/import -file JMdict_e.gz
+//	go run ./cmd/import -file JMdict_e.gz -out entries.gob.gz
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/gilmoreg/seibiki/internal/connectors/mongodb"
+	"github.com/gilmoreg/seibiki/internal/dictionary"
+	"github.com/gilmoreg/seibiki/internal/jmdict"
+	"github.com/mongodb/mongo-go-driver/bson"
+	"go.uber.org/zap"
+)
+
+func main() {
+	file := flag.String("file", "", "path to JMdict XML, optionally gzipped")
+	out := flag.String("out", "", "write a .json or .gob export (optionally .gz) instead of MongoDB")
+	conn := flag.String("mongo", os.Getenv("MONGODB_CONNECTION_STRING"), "MongoDB connection string")
+	database := flag.String("database", os.Getenv("MONGODB_DATABASE"), "MongoDB database (default jedict)")
+	collection := flag.String("collection", os.Getenv("MONGODB_COLLECTION"), "MongoDB collection (default entries)")
+	batch := flag.Int("batch", 1000, "entries per bulk write")
+	flag.Parse()
+
+	l := zap.NewExample().Sugar()
+	defer l.Sync()
+
+	if *file == "" {
+		flag.Usage()
+		os.Exit(2)
+	}
+
+	start := time.Now()
+	if *out != "" {
+		err := export(*file, *out, l)
+		if err != nil {
+			l.Fatal(err)
+		}
+	} else {
+		opts := mongodb.Options{Database: *database, Collection: *collection}
+		err := upsert(*file, *conn, opts, *batch, l)
+		if err != nil {
+			l.Fatal(err)
+		}
+	}
+	l.Info(fmt.Sprintf("done. %s elapsed", time.Since(start)))
+}
+
+// export - convert the XML to an export file for the memory backend
+func export(file, out string, l *zap.SugaredLogger) error {
+	entries, err := jmdict.Load(file)
+	if err != nil {
+		return err
+	}
+	l.Info(fmt.Sprintf("writing %d entries to %s", len(entries), out))
+	return jmdict.Save(out, entries)
+}
+
+// upsert - stream the XML into MongoDB in batches keyed by sequence
+func upsert(file, conn string, opts mongodb.Options, batch int, l *zap.SugaredLogger) error {
+	m, err := mongodb.NewWriter(conn, opts, l)
+	if err != nil {
+		return err
+	}
+	ctx := context.Background()
+	err = m.EnsureIndexes(ctx)
+	if err != nil {
+		return err
+	}
+
+	var total mongodb.UpsertResult
+	pending := make([]mongodb.Upsert, 0, batch)
+	flush := func() error {
+		res, err := m.Upsert(ctx, pending)
+		if err != nil {
+			return err
+		}
+		total.Matched += res.Matched
+		total.Modified += res.Modified
+		total.Upserted += res.Upserted
+		pending = pending[:0]
+		l.Info(fmt.Sprintf("%d entries written", total.Matched+total.Upserted))
+		return nil
+	}
+
+	r, err := jmdict.Open(file)
+	if err != nil {
+		return err
+	}
+	defer r.Close()
+	err = jmdict.Parse(r, func(e dictionary.Entry) error {
+		pending = append(pending, mongodb.Upsert{
+			Filter:   bson.M{"sequence": e.Sequence},
+			Document: e,
+		})
+		if len(pending) < batch {
+			return nil
+		}
+		return flush()
+	})
+	if err != nil {
+		return err
+	}
+	err = flush()
+	if err != nil {
+		return err
+	}
+	l.Info(fmt.Sprintf("%d new, %d changed, %d unchanged",
+		total.Upserted, total.Modified, total.Matched-total.Modified))
+	if total.Upserted+total.Modified > 0 {
+		l.Info("entries changed: bump DICTIONARY_VERSION or flush the cache")
+	}
+	return nil
+}
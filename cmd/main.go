@@ -1,59 +1,282 @@
 package main
 
 import (
+	"context"
 	"fmt"
 	"net/http"
 	"os"
+	"os/signal"
+	"path/filepath"
+	"syscall"
 
+	"github.com/gilmoreg/seibiki/internal/cache"
+	"github.com/gilmoreg/seibiki/internal/config"
 	"github.com/gilmoreg/seibiki/internal/connectors/mongodb"
 	"github.com/gilmoreg/seibiki/internal/connectors/redis"
 	"github.com/gilmoreg/seibiki/internal/dictionary"
 	"github.com/gilmoreg/seibiki/internal/endpoints"
+	"github.com/gilmoreg/seibiki/internal/health"
+	"github.com/gilmoreg/seibiki/internal/jmdict"
+	"github.com/gilmoreg/seibiki/internal/logging"
+	"github.com/gilmoreg/seibiki/internal/metrics"
+	"github.com/gilmoreg/seibiki/internal/ratelimit"
 	"github.com/gilmoreg/seibiki/internal/service"
+	"github.com/gilmoreg/seibiki/internal/tracing"
 	"github.com/gorilla/mux"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
 	"go.uber.org/zap"
 )
 
 // Server - holds deps for injection
 type Server struct {
-	svc    service.LookupService
-	router *mux.Router
-	logger *zap.SugaredLogger
+	svc        service.LookupService
+	repo       dictionary.Repository
+	router     *mux.Router
+	logger     *zap.SugaredLogger
+	adminToken string
+	wwwroot    string
+	checks     []health.Check
+	metrics    metrics.Metrics
+	limits     endpoints.Limits
+	userData   *userData
+	// limit - rate limit for the lookup and search routes
+	limit mux.MiddlewareFunc
 }
 
 // Routes - add routes
 func (s *Server) Routes() {
-	s.router.Path("/api/lookup").Methods("POST").Handler(endpoints.Handler(s.svc))
-	s.router.
-		PathPrefix("/static/js/").
-		Handler(http.StripPrefix("/static/js/", http.FileServer(http.Dir("/go/bin/wwwroot/static/js/"))))
-	s.router.
-		PathPrefix("/static/css/").
-		Handler(http.StripPrefix("/static/css/", http.FileServer(http.Dir("/go/bin/wwwroot/static/css/"))))
-	s.router.
-		PathPrefix("/static/media/").
-		Handler(http.StripPrefix("/static/media/", http.FileServer(http.Dir("/go/bin/wwwroot/static/media/"))))
-	s.router.Handle("/", http.FileServer(http.Dir("/go/bin/wwwroot/")))
+	s.router.Use(endpoints.TraceRequests())
+	s.router.Use(endpoints.LogRequests(s.logger))
+	s.router.Use(endpoints.InstrumentRoutes(s.metrics.Requests, s.metrics.RequestLatency))
+	s.router.Path("/metrics").Methods("GET").Handler(promhttp.Handler())
+	s.router.Path("/healthz").Methods("GET").Handler(endpoints.LiveHandler())
+	s.router.Path("/readyz").Methods("GET").Handler(endpoints.ReadyHandler(s.checks))
+	in := endpoints.Instruments{Tokens: s.metrics.Tokens, Meanings: s.metrics.Meanings}
+	s.router.Path("/api/lookup").Methods("POST").Handler(s.limit(endpoints.Handler(s.svc, s.limits, in)))
+	s.router.Path("/api/lookup/stream").Methods("POST").Handler(s.limit(endpoints.StreamHandler(s.svc, s.limits, in)))
+	// repo is the undecorated dictionary, so the glossary is added to searches here
+	if searcher, ok := s.repo.(dictionary.Searcher); ok {
+		searcher = dictionary.SearchWithGlossary(searcher, s.userData.glossary)
+		s.router.Path("/api/entries").Methods("GET").Handler(s.limit(endpoints.SearchHandler(searcher)))
+		s.router.Path("/api/entries/{sequence:[0-9]+}").Methods("GET").Handler(s.limit(endpoints.EntryHandler(searcher)))
+	}
+	if inv, ok := s.repo.(dictionary.Invalidator); ok && s.adminToken != "" {
+		s.router.Path("/api/admin/cache/flush").Methods("POST").Handler(endpoints.FlushHandler(inv, s.adminToken))
+	}
+	if s.adminToken != "" {
+		s.router.Path("/api/admin/user-dictionary/reload").Methods("POST").Handler(endpoints.ReloadHandler(s.userData.Reload, s.adminToken))
+	}
+	for _, dir := range []string{"/static/js/", "/static/css/", "/static/media/"} {
+		s.router.
+			PathPrefix(dir).
+			Handler(http.StripPrefix(dir, http.FileServer(http.Dir(filepath.Join(s.wwwroot, dir)))))
+	}
+	s.router.Handle("/", http.FileServer(http.Dir(s.wwwroot)))
 }
 
 func main() {
-	l := zap.NewExample().Sugar()
+	c, err := config.Load(os.Args[1:])
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(2)
+	}
+	l, err := logging.New(c.Log.Level, c.Log.Format)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(2)
+	}
 	defer l.Sync()
+	shutdownTracing, err := tracing.Setup(context.Background(), c.Tracing.Exporter, c.Tracing.Endpoint)
+	if err != nil {
+		l.Fatal(err)
+	}
 	r := mux.NewRouter()
-	c := redis.New(os.Getenv("REDIS_URL"), l)
-	m, err := mongodb.New(os.Getenv("MONGODB_CONNECTION_STRING"), l)
+	m := metrics.New()
+	d, rc, err := newRepository(c, m, l)
 	if err != nil {
-		panic(err)
+		l.Fatalw("could not create repository", "err", err)
+	}
+	tok, err := newTokenizer(c, l)
+	if err != nil {
+		l.Fatal(err)
+	}
+	u := &userData{
+		tok:          tok,
+		glossary:     dictionary.NewGlossary(),
+		userDicPath:  c.Tokenizer.UserDictionary,
+		glossaryPath: c.Dictionary.Glossary,
+		logger:       l,
+	}
+	if _, _, err := u.Reload(context.Background()); err != nil {
+		l.Fatal(err)
+	}
+	repo := dictionary.WithGlossary(dictionary.NewInstrumented(d, m.DictionaryLatency), u.glossary)
+	svc := service.InstrumentingMiddleware(m.Lookups, m.LookupLatency)(service.New(l, repo, tok))
+	var limiter *ratelimit.Limiter
+	if c.Limits.RequestsPerMinute > 0 {
+		limiter = ratelimit.New(c.Limits.RequestsPerMinute, c.Limits.Burst)
 	}
-	d := dictionary.New(m, c, l)
-	svc := service.New(l, d)
 	s := Server{
-		router: r,
-		svc:    svc,
-		logger: l,
+		router:     r,
+		svc:        svc,
+		repo:       d,
+		logger:     l,
+		adminToken: c.AdminToken,
+		wwwroot:    c.WWWRoot,
+		checks:     readinessChecks(tok, d, rc),
+		metrics:    m,
+		userData:   u,
+		limits: endpoints.Limits{
+			MaxBodyBytes:   int64(c.Limits.MaxBodyBytes),
+			MaxQueryLength: c.Limits.MaxQueryLength,
+		},
+		limit: endpoints.LimitRequests(limiter, c.Limits.APIKeys, c.Limits.TrustProxy),
 	}
 	s.Routes()
-	url := fmt.Sprintf(":%s", os.Getenv("PORT"))
-	l.Info(fmt.Sprintf("starting server at %s", url))
-	http.ListenAndServe(url, r)
+	srv := &http.Server{
+		Addr:         fmt.Sprintf(":%s", c.Port),
+		Handler:      r,
+		ReadTimeout:  c.HTTP.ReadTimeout,
+		WriteTimeout: c.HTTP.WriteTimeout,
+		IdleTimeout:  c.HTTP.IdleTimeout,
+	}
+
+	serveErr := make(chan error, 1)
+	go func() {
+		l.Info(fmt.Sprintf("starting server at %s", srv.Addr))
+		serveErr <- srv.ListenAndServe()
+	}()
+	stop := make(chan os.Signal, 1)
+	signal.Notify(stop, syscall.SIGINT, syscall.SIGTERM)
+	// SIGHUP reloads the user dictionary and glossary
+	hup := make(chan os.Signal, 1)
+	signal.Notify(hup, syscall.SIGHUP)
+wait:
+	for {
+		select {
+		case err := <-serveErr:
+			l.Fatal(err)
+		case <-hup:
+			if _, _, err := u.Reload(context.Background()); err != nil {
+				l.Errorf("error reloading user data: %s", err.Error())
+			}
+		case sig := <-stop:
+			l.Info(fmt.Sprintf("received %s, shutting down", sig))
+			break wait
+		}
+	}
+	signal.Stop(stop)
+	signal.Stop(hup)
+
+	ctx, cancel := context.WithTimeout(context.Background(), c.HTTP.ShutdownTimeout)
+	defer cancel()
+	if err := srv.Shutdown(ctx); err != nil {
+		l.Errorf("error draining requests: %s", err.Error())
+	}
+	if closer, ok := d.(dictionary.Closer); ok {
+		if err := closer.Close(ctx); err != nil {
+			l.Errorf("error closing dictionary: %s", err.Error())
+		}
+	}
+	if rc != nil {
+		if err := rc.Close(); err != nil {
+			l.Errorf("error closing redis: %s", err.Error())
+		}
+	}
+	if err := shutdownTracing(ctx); err != nil {
+		l.Errorf("error flushing traces: %s", err.Error())
+	}
+	l.Info("stopped")
+}
+
+// readinessChecks - dependencies reported by /readyz
+// The db and tokenizer are required; Redis is not, as lookups skip a failing cache
+func readinessChecks(tok *dictionary.Tokenizer, d dictionary.Repository, rc redis.Client) []health.Check {
+	checks := []health.Check{{
+		Name:     "tokenizer",
+		Required: true,
+		Ping:     func(context.Context) error { return tok.Check() },
+	}}
+	if p, ok := d.(dictionary.Pinger); ok {
+		checks = append(checks, health.Check{Name: "mongodb", Required: true, Ping: p.Ping})
+	}
+	if rc != nil {
+		checks = append(checks, health.Check{Name: "redis", Ping: rc.Ping})
+	}
+	return checks
+}
+
+// newTokenizer - Kagome with each of tokenizer.dictionaries loaded
+func newTokenizer(c config.Config, l *zap.SugaredLogger) (*dictionary.Tokenizer, error) {
+	sysdics := make([]dictionary.SysDic, 0)
+	for _, d := range c.Tokenizer.Dictionaries {
+		sysdics = append(sysdics, dictionary.SysDic(d))
+	}
+	l.Info(fmt.Sprintf("loading tokenizer dictionaries %v", c.Tokenizer.Dictionaries))
+	return dictionary.NewTokenizer(sysdics, dictionary.Mode(c.Tokenizer.Mode))
+}
+
+// newRepository - dictionary backend chosen by dictionary.backend
+// "mongodb" uses MongoDB with a cache in front,
+// "memory" loads dictionary.file (JMdict XML, or a JSON/gob export) into memory
+// Also returns the Redis client behind the cache, if there is one, for closing on shutdown
+func newRepository(c config.Config, met metrics.Metrics, l *zap.SugaredLogger) (dictionary.Repository, redis.Client, error) {
+	switch c.Dictionary.Backend {
+	case "mongodb":
+		ch, rc, err := newCache(c, l)
+		if err != nil {
+			return nil, nil, err
+		}
+		ch = cache.NewTraced(cache.NewInstrumented(ch, met.Cache))
+		m, err := mongodb.New(c.MongoDB.URI, mongodb.Options{
+			Database:    c.MongoDB.Database,
+			Collection:  c.MongoDB.Collection,
+			MaxPoolSize: uint16(c.MongoDB.MaxPoolSize),
+			Timeout:     c.MongoDB.Timeout,
+		}, l)
+		if err != nil {
+			return nil, nil, err
+		}
+		m = mongodb.NewInstrumented(m, met.DBLatency)
+		return dictionary.New(m, ch, c.Dictionary.Version, l), rc, nil
+	case "memory":
+		l.Info(fmt.Sprintf("loading dictionary from %s", c.Dictionary.File))
+		entries, err := jmdict.Load(c.Dictionary.File)
+		if err != nil {
+			return nil, nil, err
+		}
+		l.Info(fmt.Sprintf("loaded %d entries", len(entries)))
+		return dictionary.NewMemory(entries), nil, nil
+	default:
+		return nil, nil, fmt.Errorf("unknown dictionary backend %q", c.Dictionary.Backend)
+	}
+}
+
+// newCache - cache chosen by cache.type
+// "redis", "lru" (in-process, cache.size entries),
+// "tiered" (lru in front of redis) or "none"
+// Entries expire after cache.ttl (0 for never)
+func newCache(c config.Config, l *zap.SugaredLogger) (cache.Cache, redis.Client, error) {
+	newRedis := func() redis.Client {
+		return redis.New(c.Redis.URL, redis.Options{
+			MaxIdle:        c.Redis.MaxIdle,
+			MaxActive:      c.Redis.MaxActive,
+			IdleTimeout:    c.Redis.IdleTimeout,
+			CommandTimeout: c.Redis.CommandTimeout,
+		}, l)
+	}
+	switch c.Cache.Type {
+	case "redis":
+		rc := newRedis()
+		return cache.NewRedis(rc, c.Cache.TTL), rc, nil
+	case "lru":
+		return cache.NewLRU(c.Cache.Size, c.Cache.TTL), nil, nil
+	case "tiered":
+		rc := newRedis()
+		return cache.NewTiered(cache.NewLRU(c.Cache.Size, c.Cache.TTL), cache.NewRedis(rc, c.Cache.TTL)), rc, nil
+	case "none":
+		return cache.NewNop(), nil, nil
+	default:
+		return nil, nil, fmt.Errorf("unknown cache %q", c.Cache.Type)
+	}
 }
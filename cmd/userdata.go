@@ -0,0 +1,43 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"sync"
+
+	"github.com/gilmoreg/seibiki/internal/dictionary"
+	"github.com/gilmoreg/seibiki/internal/jmdict"
+	"go.uber.org/zap"
+)
+
+// userData - the user dictionary and glossary files, (re)loaded into
+// the tokenizer and glossary; either path may be empty
+type userData struct {
+	mu           sync.Mutex
+	tok          *dictionary.Tokenizer
+	glossary     *dictionary.Glossary
+	userDicPath  string
+	glossaryPath string
+	logger       *zap.SugaredLogger
+}
+
+// Reload - read both files again
+// Nothing is replaced unless both can be read
+func (u *userData) Reload(ctx context.Context) (int, int, error) {
+	u.mu.Lock()
+	defer u.mu.Unlock()
+	entries := []dictionary.Entry{}
+	if u.glossaryPath != "" {
+		var err error
+		if entries, err = jmdict.Load(u.glossaryPath); err != nil {
+			return 0, 0, fmt.Errorf("glossary: %s", err)
+		}
+	}
+	words, err := u.tok.LoadUserDic(u.userDicPath)
+	if err != nil {
+		return 0, 0, fmt.Errorf("user dictionary: %s", err)
+	}
+	u.glossary.Set(entries)
+	u.logger.Info(fmt.Sprintf("loaded %d user dictionary words and %d glossary entries", words, len(entries)))
+	return words, len(entries), nil
+}
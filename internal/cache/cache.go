@@ -0,0 +1,21 @@
+// Package cache - caches for serialized dictionary entries
+package cache
+
+import (
+	"context"
+	"errors"
+)
+
+// ErrMiss - key is not in the cache
+var ErrMiss = errors.New("cache: miss")
+
+// Cache - key/value cache
+// Get returns ErrMiss for a missing key. MGet returns one value per key,
+// nil for misses; on error it may still return the values it found.
+// Flush deletes every key starting with prefix and returns how many it found.
+type Cache interface {
+	Get(ctx context.Context, key string) ([]byte, error)
+	MGet(ctx context.Context, keys []string) ([][]byte, error)
+	Set(ctx context.Context, key string, value []byte) error
+	Flush(ctx context.Context, prefix string) (int, error)
+}
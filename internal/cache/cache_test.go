@@ -0,0 +1,158 @@
+package cache
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/gilmoreg/seibiki/internal/metrics/metricstest"
+	"github.com/stretchr/testify/assert"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/codes"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+)
+
+func TestLRU(t *testing.T) {
+	ctx := context.Background()
+	c := NewLRU(2, 0)
+	c.Set(ctx, "a", []byte("1"))
+	c.Set(ctx, "b", []byte("2"))
+	// touch a so b is the oldest
+	c.Get(ctx, "a")
+	c.Set(ctx, "c", []byte("3"))
+
+	_, err := c.Get(ctx, "b")
+	assert.Equal(t, ErrMiss, err)
+	res, err := c.MGet(ctx, []string{"a", "b", "c"})
+	assert.Nil(t, err)
+	assert.Equal(t, "1", string(res[0]))
+	assert.Nil(t, res[1])
+	assert.Equal(t, "3", string(res[2]))
+}
+
+func TestLRUExpiry(t *testing.T) {
+	ctx := context.Background()
+	c := NewLRU(2, time.Millisecond)
+	c.Set(ctx, "a", []byte("1"))
+	time.Sleep(5 * time.Millisecond)
+	_, err := c.Get(ctx, "a")
+	assert.Equal(t, ErrMiss, err)
+}
+
+func TestNamespace(t *testing.T) {
+	ctx := context.Background()
+	backend := NewLRU(10, 0)
+	v1 := NewNamespace(backend, "v1:")
+	v2 := NewNamespace(backend, "v2:")
+	v1.Set(ctx, "a", []byte("1"))
+	v2.Set(ctx, "a", []byte("2"))
+
+	value, err := backend.Get(ctx, "v1:a")
+	assert.Nil(t, err)
+	assert.Equal(t, "1", string(value))
+
+	n, err := v1.Flush(ctx, "")
+	assert.Nil(t, err)
+	assert.Equal(t, 1, n)
+	_, err = v1.Get(ctx, "a")
+	assert.Equal(t, ErrMiss, err)
+	value, err = v2.Get(ctx, "a")
+	assert.Nil(t, err)
+	assert.Equal(t, "2", string(value))
+}
+
+func TestTiered(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("fills front from back", func(t *testing.T) {
+		front, back := NewLRU(10, 0), NewLRU(10, 0)
+		back.Set(ctx, "a", []byte("1"))
+		c := NewTiered(front, back)
+		res, err := c.MGet(ctx, []string{"a", "b"})
+		assert.Nil(t, err)
+		assert.Equal(t, "1", string(res[0]))
+		assert.Nil(t, res[1])
+		value, err := front.Get(ctx, "a")
+		assert.Nil(t, err)
+		assert.Equal(t, "1", string(value))
+	})
+
+	t.Run("serves front when back is down", func(t *testing.T) {
+		front := NewLRU(10, 0)
+		front.Set(ctx, "a", []byte("1"))
+		c := NewTiered(front, down{})
+		res, err := c.MGet(ctx, []string{"a", "b"})
+		assert.NotNil(t, err)
+		assert.Equal(t, "1", string(res[0]))
+		assert.Nil(t, res[1])
+		value, err := c.Get(ctx, "a")
+		assert.Nil(t, err)
+		assert.Equal(t, "1", string(value))
+	})
+}
+
+func TestNop(t *testing.T) {
+	ctx := context.Background()
+	c := NewNop()
+	assert.Nil(t, c.Set(ctx, "a", []byte("1")))
+	_, err := c.Get(ctx, "a")
+	assert.Equal(t, ErrMiss, err)
+	res, err := c.MGet(ctx, []string{"a"})
+	assert.Nil(t, err)
+	assert.Nil(t, res[0])
+}
+
+type down struct{}
+
+func (down) Get(ctx context.Context, key string) ([]byte, error) {
+	return nil, errors.New("connection refused")
+}
+
+func (down) MGet(ctx context.Context, keys []string) ([][]byte, error) {
+	return nil, errors.New("connection refused")
+}
+
+func (down) Set(ctx context.Context, key string, value []byte) error {
+	return errors.New("connection refused")
+}
+
+func (down) Flush(ctx context.Context, prefix string) (int, error) {
+	return 0, errors.New("connection refused")
+}
+
+func TestInstrumented(t *testing.T) {
+	ctx := context.Background()
+	reads := metricstest.NewCounter()
+	c := NewInstrumented(NewLRU(10, 0), reads)
+	c.Set(ctx, "a", []byte("1"))
+	c.Get(ctx, "a")
+	c.Get(ctx, "b")
+	c.MGet(ctx, []string{"a", "b", "c"})
+	assert.Equal(t, 2.0, reads.Value("result", "hit"))
+	assert.Equal(t, 3.0, reads.Value("result", "miss"))
+
+	c = NewInstrumented(down{}, reads)
+	c.Get(ctx, "a")
+	c.MGet(ctx, []string{"a", "b"})
+	assert.Equal(t, 2.0, reads.Value("result", "error"))
+	assert.Equal(t, 3.0, reads.Value("result", "miss"))
+}
+
+func TestTraced(t *testing.T) {
+	spans := tracetest.NewSpanRecorder()
+	otel.SetTracerProvider(sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(spans)))
+	ctx := context.Background()
+	c := NewTraced(NewLRU(10, 0))
+	c.Set(ctx, "a", []byte("1"))
+	c.Get(ctx, "b")
+	NewTraced(down{}).Get(ctx, "a")
+
+	ended := spans.Ended()
+	assert.Len(t, ended, 3)
+	assert.Equal(t, "cache.Set", ended[0].Name())
+	assert.Equal(t, "cache.Get", ended[1].Name())
+	assert.Equal(t, codes.Unset, ended[1].Status().Code, "a miss is not an error")
+	assert.Equal(t, codes.Error, ended[2].Status().Code)
+}
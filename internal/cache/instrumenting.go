@@ -0,0 +1,53 @@
+package cache
+
+import (
+	"context"
+
+	"github.com/go-kit/kit/metrics"
+)
+
+// instrumented - counts the results of reads from the Cache it wraps
+type instrumented struct {
+	Cache
+	reads metrics.Counter
+}
+
+// NewInstrumented - Cache counting reads from c in reads,
+// labelled "result": hit, miss or error
+// MGet counts each key, and one error if it fails
+func NewInstrumented(c Cache, reads metrics.Counter) Cache {
+	return &instrumented{Cache: c, reads: reads}
+}
+
+func (c *instrumented) Get(ctx context.Context, key string) ([]byte, error) {
+	value, err := c.Cache.Get(ctx, key)
+	switch err {
+	case nil:
+		c.reads.With("result", "hit").Add(1)
+	case ErrMiss:
+		c.reads.With("result", "miss").Add(1)
+	default:
+		c.reads.With("result", "error").Add(1)
+	}
+	return value, err
+}
+
+func (c *instrumented) MGet(ctx context.Context, keys []string) ([][]byte, error) {
+	values, err := c.Cache.MGet(ctx, keys)
+	if err != nil {
+		c.reads.With("result", "error").Add(1)
+	}
+	hits := 0
+	for _, value := range values {
+		if value != nil {
+			hits++
+		}
+	}
+	if hits > 0 {
+		c.reads.With("result", "hit").Add(float64(hits))
+	}
+	if err == nil && hits < len(keys) {
+		c.reads.With("result", "miss").Add(float64(len(keys) - hits))
+	}
+	return values, err
+}
@@ -0,0 +1,37 @@
+package cache
+
+import (
+	"context"
+)
+
+// namespace - prefixes every key, so one backend can hold several
+// independent caches and each can be flushed on its own
+type namespace struct {
+	cache  Cache
+	prefix string
+}
+
+// NewNamespace - Cache storing its keys in c under prefix
+func NewNamespace(c Cache, prefix string) Cache {
+	return &namespace{cache: c, prefix: prefix}
+}
+
+func (n *namespace) Get(ctx context.Context, key string) ([]byte, error) {
+	return n.cache.Get(ctx, n.prefix+key)
+}
+
+func (n *namespace) MGet(ctx context.Context, keys []string) ([][]byte, error) {
+	prefixed := make([]string, len(keys))
+	for i, key := range keys {
+		prefixed[i] = n.prefix + key
+	}
+	return n.cache.MGet(ctx, prefixed)
+}
+
+func (n *namespace) Set(ctx context.Context, key string, value []byte) error {
+	return n.cache.Set(ctx, n.prefix+key, value)
+}
+
+func (n *namespace) Flush(ctx context.Context, prefix string) (int, error) {
+	return n.cache.Flush(ctx, n.prefix+prefix)
+}
@@ -0,0 +1,28 @@
+package cache
+
+import (
+	"context"
+)
+
+type nop struct{}
+
+// NewNop - Cache that stores nothing, for deployments without a cache
+func NewNop() Cache {
+	return nop{}
+}
+
+func (nop) Get(ctx context.Context, key string) ([]byte, error) {
+	return nil, ErrMiss
+}
+
+func (nop) MGet(ctx context.Context, keys []string) ([][]byte, error) {
+	return make([][]byte, len(keys)), nil
+}
+
+func (nop) Set(ctx context.Context, key string, value []byte) error {
+	return nil
+}
+
+func (nop) Flush(ctx context.Context, prefix string) (int, error) {
+	return 0, nil
+}
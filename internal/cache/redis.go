@@ -0,0 +1,40 @@
+package cache
+
+import (
+	"context"
+	"time"
+
+	"github.com/gilmoreg/seibiki/internal/connectors/redis"
+	redigo "github.com/gomodule/redigo/redis"
+)
+
+type redisCache struct {
+	client redis.Client
+	ttl    time.Duration
+}
+
+// NewRedis - Cache backed by Redis
+// Keys expire after ttl, or never if ttl is 0
+func NewRedis(client redis.Client, ttl time.Duration) Cache {
+	return &redisCache{client: client, ttl: ttl}
+}
+
+func (c *redisCache) Get(ctx context.Context, key string) ([]byte, error) {
+	data, err := c.client.Get(ctx, key)
+	if err == redigo.ErrNil {
+		return nil, ErrMiss
+	}
+	return data, err
+}
+
+func (c *redisCache) MGet(ctx context.Context, keys []string) ([][]byte, error) {
+	return c.client.MGet(ctx, keys)
+}
+
+func (c *redisCache) Set(ctx context.Context, key string, value []byte) error {
+	return c.client.Set(ctx, key, value, c.ttl)
+}
+
+func (c *redisCache) Flush(ctx context.Context, prefix string) (int, error) {
+	return c.client.DeletePrefix(ctx, prefix)
+}
@@ -0,0 +1,68 @@
+package cache
+
+import (
+	"context"
+)
+
+// tiered - fast cache in front of a slower shared one
+type tiered struct {
+	front Cache
+	back  Cache
+}
+
+// NewTiered - Cache checking front first, then back
+// Hits from back are copied into front. If back fails,
+// whatever front holds is still returned alongside the error.
+func NewTiered(front, back Cache) Cache {
+	return &tiered{front: front, back: back}
+}
+
+func (c *tiered) Get(ctx context.Context, key string) ([]byte, error) {
+	value, err := c.front.Get(ctx, key)
+	if err == nil {
+		return value, nil
+	}
+	value, err = c.back.Get(ctx, key)
+	if err != nil {
+		return nil, err
+	}
+	c.front.Set(ctx, key, value)
+	return value, nil
+}
+
+func (c *tiered) MGet(ctx context.Context, keys []string) ([][]byte, error) {
+	result, err := c.front.MGet(ctx, keys)
+	if err != nil || len(result) != len(keys) {
+		result = make([][]byte, len(keys))
+	}
+	misses := make([]string, 0)
+	positions := make([]int, 0)
+	for i, value := range result {
+		if value == nil {
+			misses = append(misses, keys[i])
+			positions = append(positions, i)
+		}
+	}
+	if len(misses) == 0 {
+		return result, nil
+	}
+	values, err := c.back.MGet(ctx, misses)
+	for n, value := range values {
+		if n >= len(positions) || value == nil {
+			continue
+		}
+		result[positions[n]] = value
+		c.front.Set(ctx, misses[n], value)
+	}
+	return result, err
+}
+
+func (c *tiered) Set(ctx context.Context, key string, value []byte) error {
+	c.front.Set(ctx, key, value)
+	return c.back.Set(ctx, key, value)
+}
+
+func (c *tiered) Flush(ctx context.Context, prefix string) (int, error) {
+	c.front.Flush(ctx, prefix)
+	return c.back.Flush(ctx, prefix)
+}
@@ -0,0 +1,77 @@
+package cache
+
+import (
+	"context"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+)
+
+var tracer = otel.Tracer("github.com/gilmoreg/seibiki/internal/cache")
+
+// traced - starts a span for every call to the Cache it wraps
+type traced struct {
+	Cache
+}
+
+// NewTraced - Cache recording a span for each call to c,
+// with whether Get hit and how many keys MGet found
+func NewTraced(c Cache) Cache {
+	return &traced{Cache: c}
+}
+
+func (c *traced) Get(ctx context.Context, key string) ([]byte, error) {
+	ctx, span := tracer.Start(ctx, "cache.Get")
+	defer span.End()
+	value, err := c.Cache.Get(ctx, key)
+	span.SetAttributes(attribute.Bool("cache.hit", err == nil))
+	if err != nil && err != ErrMiss {
+		fail(span, err)
+	}
+	return value, err
+}
+
+func (c *traced) MGet(ctx context.Context, keys []string) ([][]byte, error) {
+	ctx, span := tracer.Start(ctx, "cache.MGet")
+	defer span.End()
+	values, err := c.Cache.MGet(ctx, keys)
+	hits := 0
+	for _, value := range values {
+		if value != nil {
+			hits++
+		}
+	}
+	span.SetAttributes(attribute.Int("cache.keys", len(keys)), attribute.Int("cache.hits", hits))
+	if err != nil {
+		fail(span, err)
+	}
+	return values, err
+}
+
+func (c *traced) Set(ctx context.Context, key string, value []byte) error {
+	ctx, span := tracer.Start(ctx, "cache.Set")
+	defer span.End()
+	err := c.Cache.Set(ctx, key, value)
+	if err != nil {
+		fail(span, err)
+	}
+	return err
+}
+
+func (c *traced) Flush(ctx context.Context, prefix string) (int, error) {
+	ctx, span := tracer.Start(ctx, "cache.Flush")
+	defer span.End()
+	n, err := c.Cache.Flush(ctx, prefix)
+	span.SetAttributes(attribute.Int("cache.flushed", n))
+	if err != nil {
+		fail(span, err)
+	}
+	return n, err
+}
+
+func fail(span trace.Span, err error) {
+	span.RecordError(err)
+	span.SetStatus(codes.Error, err.Error())
+}
@@ -0,0 +1,367 @@
+// Package config loads server settings from defaults, a YAML file,
+// environment variables and command line flags, each overriding the last
+//
+//	seibiki -config seibiki.yaml -port 8080
+package config
+
+import (
+	"flag"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"strings"
+	"time"
+
+	yaml "gopkg.in/yaml.v2"
+)
+
+// Config - every setting the server needs
+type Config struct {
+	Port    string `yaml:"port"`
+	WWWRoot string `yaml:"wwwroot"`
+	// AdminToken - bearer token for /api/admin; admin routes are off without it
+	AdminToken string     `yaml:"admin_token"`
+	HTTP       HTTP       `yaml:"http"`
+	Limits     Limits     `yaml:"limits"`
+	Log        Log        `yaml:"log"`
+	Tracing    Tracing    `yaml:"tracing"`
+	Tokenizer  Tokenizer  `yaml:"tokenizer"`
+	Dictionary Dictionary `yaml:"dictionary"`
+	MongoDB    MongoDB    `yaml:"mongodb"`
+	Redis      Redis      `yaml:"redis"`
+	Cache      Cache      `yaml:"cache"`
+}
+
+// HTTP - server timeouts
+type HTTP struct {
+	ReadTimeout  time.Duration `yaml:"read_timeout"`
+	WriteTimeout time.Duration `yaml:"write_timeout"`
+	IdleTimeout  time.Duration `yaml:"idle_timeout"`
+	// ShutdownTimeout - time given to requests and cache writes
+	// in flight to finish after SIGTERM or SIGINT
+	ShutdownTimeout time.Duration `yaml:"shutdown_timeout"`
+}
+
+// Limits - protect the lookup API from large or frequent requests
+type Limits struct {
+	// MaxBodyBytes - largest lookup request body accepted
+	MaxBodyBytes int `yaml:"max_body_bytes"`
+	// MaxQueryLength - longest query accepted, in characters
+	MaxQueryLength int `yaml:"max_query_length"`
+	// RequestsPerMinute - lookups each client may make, 0 for no limit
+	RequestsPerMinute int `yaml:"requests_per_minute"`
+	// Burst - lookups a client may make at once
+	Burst int `yaml:"burst"`
+	// APIKeys - keys clients can send in X-API-Key to be
+	// limited on their own rather than by IP
+	APIKeys []string `yaml:"api_keys"`
+	// TrustProxy - take client IPs from the last X-Forwarded-For entry;
+	// only set this behind a reverse proxy that appends it
+	TrustProxy bool `yaml:"trust_proxy"`
+}
+
+// Log - server log output
+type Log struct {
+	// Level - "debug", "info", "warn" or "error"
+	Level string `yaml:"level"`
+	// Format - "json", or "console" for reading in a terminal
+	Format string `yaml:"format"`
+}
+
+// Tracing - OpenTelemetry trace export
+type Tracing struct {
+	// Exporter - "otlp", "stdout" or "none"
+	Exporter string `yaml:"exporter"`
+	// Endpoint - OTLP/HTTP collector host:port; if empty the
+	// standard OTEL_EXPORTER_OTLP_* variables are used
+	Endpoint string `yaml:"endpoint"`
+}
+
+// Tokenizer - how queries are split into words
+type Tokenizer struct {
+	// Dictionaries - Kagome dictionaries to load, "ipadic" and/or
+	// "unidic"; requests use the first unless they ask for another
+	Dictionaries []string `yaml:"dictionaries"`
+	// Mode - "normal", "search" or "extended", unless a request asks
+	Mode string `yaml:"mode"`
+	// UserDictionary - optional Kagome user dictionary CSV, reloaded
+	// on SIGHUP or through the admin API
+	UserDictionary string `yaml:"user_dictionary"`
+}
+
+// Dictionary - where entries come from
+type Dictionary struct {
+	// Backend - "mongodb" or "memory"
+	Backend string `yaml:"backend"`
+	// File - JMdict XML or export loaded by the memory backend
+	File string `yaml:"file"`
+	// Version - part of every cache key; bump it after a re-import
+	Version string `yaml:"version"`
+	// Glossary - optional JMdict XML or export of user-defined entries,
+	// returned with the dictionary's and reloaded with the user dictionary
+	Glossary string `yaml:"glossary"`
+}
+
+// MongoDB - connection to the entries collection
+type MongoDB struct {
+	URI         string        `yaml:"uri"`
+	Database    string        `yaml:"database"`
+	Collection  string        `yaml:"collection"`
+	MaxPoolSize int           `yaml:"max_pool_size"`
+	Timeout     time.Duration `yaml:"timeout"`
+}
+
+// Redis - connection pool for the redis and tiered caches
+type Redis struct {
+	URL         string        `yaml:"url"`
+	MaxIdle     int           `yaml:"max_idle"`
+	MaxActive   int           `yaml:"max_active"`
+	IdleTimeout time.Duration `yaml:"idle_timeout"`
+	// CommandTimeout - longest to wait for a reply, even when the
+	// request that sent the command has no deadline
+	CommandTimeout time.Duration `yaml:"command_timeout"`
+}
+
+// Cache - lookup cache in front of MongoDB
+type Cache struct {
+	// Type - "redis", "lru", "tiered" or "none"
+	Type string `yaml:"type"`
+	// Size - entries held by the in-process LRU
+	Size int `yaml:"size"`
+	// TTL - how long entries are cached, 0 for ever
+	TTL time.Duration `yaml:"ttl"`
+}
+
+// Default - settings used when nothing else is given
+func Default() Config {
+	return Config{
+		Port:    "3001",
+		WWWRoot: "/go/bin/wwwroot",
+		HTTP: HTTP{
+			ReadTimeout:  10 * time.Second,
+			WriteTimeout: 60 * time.Second,
+			IdleTimeout:  2 * time.Minute,
+			// Below the 30s Docker and Kubernetes give before killing
+			ShutdownTimeout: 25 * time.Second,
+		},
+		Limits: Limits{
+			MaxBodyBytes:      64 << 10,
+			MaxQueryLength:    10000,
+			RequestsPerMinute: 120,
+			Burst:             20,
+		},
+		Log:        Log{Level: "info", Format: "json"},
+		Tracing:    Tracing{Exporter: "none"},
+		Tokenizer:  Tokenizer{Dictionaries: []string{"ipadic"}, Mode: "search"},
+		Dictionary: Dictionary{Backend: "mongodb", Version: "1"},
+		MongoDB: MongoDB{
+			Database:    "jedict",
+			Collection:  "entries",
+			MaxPoolSize: 100,
+			Timeout:     10 * time.Second,
+		},
+		Redis: Redis{MaxIdle: 3, IdleTimeout: 240 * time.Second, CommandTimeout: 5 * time.Second},
+		Cache: Cache{Type: "redis", Size: 10000, TTL: 24 * time.Hour},
+	}
+}
+
+// Load - settings from defaults, then the YAML file named by -config or
+// CONFIG_FILE, then environment variables, then flags in args
+func Load(args []string) (Config, error) {
+	c := Default()
+	fs := flag.NewFlagSet("seibiki", flag.ContinueOnError)
+	file := fs.String("config", os.Getenv("CONFIG_FILE"), "YAML config file (env CONFIG_FILE)")
+	env := c.bind(fs)
+	if err := fs.Parse(args); err != nil {
+		return c, err
+	}
+
+	// Parsing the flags wrote them into c already; start again
+	// from the defaults and put them back on top at the end
+	set := make(map[string]string)
+	fs.Visit(func(f *flag.Flag) {
+		set[f.Name] = f.Value.String()
+	})
+	c = Default()
+	if *file != "" {
+		if err := c.loadFile(*file); err != nil {
+			return c, err
+		}
+	}
+	for name, key := range env {
+		if v, ok := os.LookupEnv(key); ok {
+			if err := fs.Set(name, v); err != nil {
+				return c, fmt.Errorf("invalid %s %q: %s", key, v, err)
+			}
+		}
+	}
+	for name, v := range set {
+		fs.Set(name, v)
+	}
+	return c, c.Validate()
+}
+
+func (c *Config) loadFile(path string) error {
+	b, err := ioutil.ReadFile(path)
+	if err != nil {
+		return err
+	}
+	if err := yaml.UnmarshalStrict(b, c); err != nil {
+		return fmt.Errorf("%s: %s", path, err)
+	}
+	return nil
+}
+
+// bind - register a flag for every setting, writing into c
+// Returns the environment variable read for each flag
+func (c *Config) bind(fs *flag.FlagSet) map[string]string {
+	env := make(map[string]string)
+	str := func(p *string, name, key, usage string) {
+		fs.StringVar(p, name, *p, usage+" (env "+key+")")
+		env[name] = key
+	}
+	num := func(p *int, name, key, usage string) {
+		fs.IntVar(p, name, *p, usage+" (env "+key+")")
+		env[name] = key
+	}
+	dur := func(p *time.Duration, name, key, usage string) {
+		fs.DurationVar(p, name, *p, usage+" (env "+key+")")
+		env[name] = key
+	}
+	boolean := func(p *bool, name, key, usage string) {
+		fs.BoolVar(p, name, *p, usage+" (env "+key+")")
+		env[name] = key
+	}
+	list := func(p *[]string, name, key, usage string) {
+		fs.Var((*stringList)(p), name, usage+", comma separated (env "+key+")")
+		env[name] = key
+	}
+
+	str(&c.Port, "port", "PORT", "port to listen on")
+	str(&c.WWWRoot, "wwwroot", "WWWROOT", "directory of the built web client")
+	str(&c.AdminToken, "admin-token", "ADMIN_TOKEN", "bearer token for admin routes")
+	dur(&c.HTTP.ReadTimeout, "http-read-timeout", "HTTP_READ_TIMEOUT", "time to read a request")
+	dur(&c.HTTP.WriteTimeout, "http-write-timeout", "HTTP_WRITE_TIMEOUT", "time to write a response")
+	dur(&c.HTTP.IdleTimeout, "http-idle-timeout", "HTTP_IDLE_TIMEOUT", "time to keep idle connections")
+	dur(&c.HTTP.ShutdownTimeout, "http-shutdown-timeout", "HTTP_SHUTDOWN_TIMEOUT", "time to finish requests on shutdown")
+	num(&c.Limits.MaxBodyBytes, "max-body-bytes", "MAX_BODY_BYTES", "largest lookup request body")
+	num(&c.Limits.MaxQueryLength, "max-query-length", "MAX_QUERY_LENGTH", "longest query, in characters")
+	num(&c.Limits.RequestsPerMinute, "rate-limit", "RATE_LIMIT", "lookups a minute per client, 0 for no limit")
+	num(&c.Limits.Burst, "rate-limit-burst", "RATE_LIMIT_BURST", "lookups a client may make at once")
+	list(&c.Limits.APIKeys, "api-keys", "API_KEYS", "keys rate limited on their own")
+	boolean(&c.Limits.TrustProxy, "trust-proxy", "TRUST_PROXY", "take client IPs from the last X-Forwarded-For entry")
+	str(&c.Log.Level, "log-level", "LOG_LEVEL", "debug, info, warn or error")
+	str(&c.Log.Format, "log-format", "LOG_FORMAT", "json or console")
+	str(&c.Tracing.Exporter, "tracing-exporter", "TRACING_EXPORTER", "otlp, stdout or none")
+	str(&c.Tracing.Endpoint, "tracing-endpoint", "TRACING_ENDPOINT", "OTLP/HTTP collector host:port")
+	list(&c.Tokenizer.Dictionaries, "tokenizer-dictionaries", "TOKENIZER_DICTIONARIES", "ipadic and/or unidic, the first the default")
+	str(&c.Tokenizer.Mode, "tokenizer-mode", "TOKENIZER_MODE", "normal, search or extended")
+	str(&c.Tokenizer.UserDictionary, "tokenizer-user-dictionary", "TOKENIZER_USER_DICTIONARY", "Kagome user dictionary CSV")
+	str(&c.Dictionary.Backend, "dictionary-backend", "DICTIONARY_BACKEND", "mongodb or memory")
+	str(&c.Dictionary.File, "dictionary-file", "DICTIONARY_FILE", "JMdict file for the memory backend")
+	str(&c.Dictionary.Version, "dictionary-version", "DICTIONARY_VERSION", "dictionary version in cache keys")
+	str(&c.Dictionary.Glossary, "dictionary-glossary", "DICTIONARY_GLOSSARY", "JMdict file of user-defined entries")
+	str(&c.MongoDB.URI, "mongodb-uri", "MONGODB_CONNECTION_STRING", "MongoDB connection string")
+	str(&c.MongoDB.Database, "mongodb-database", "MONGODB_DATABASE", "MongoDB database")
+	str(&c.MongoDB.Collection, "mongodb-collection", "MONGODB_COLLECTION", "MongoDB collection of entries")
+	num(&c.MongoDB.MaxPoolSize, "mongodb-max-pool-size", "MONGODB_MAX_POOL_SIZE", "MongoDB connections per server")
+	dur(&c.MongoDB.Timeout, "mongodb-timeout", "MONGODB_TIMEOUT", "time to connect to MongoDB")
+	str(&c.Redis.URL, "redis-url", "REDIS_URL", "Redis URL")
+	num(&c.Redis.MaxIdle, "redis-max-idle", "REDIS_MAX_IDLE", "idle Redis connections kept")
+	num(&c.Redis.MaxActive, "redis-max-active", "REDIS_MAX_ACTIVE", "Redis connections open at once, 0 for no limit")
+	dur(&c.Redis.IdleTimeout, "redis-idle-timeout", "REDIS_IDLE_TIMEOUT", "time before idle Redis connections close")
+	dur(&c.Redis.CommandTimeout, "redis-command-timeout", "REDIS_COMMAND_TIMEOUT", "time to wait for a Redis reply")
+	str(&c.Cache.Type, "cache", "CACHE", "redis, lru, tiered or none")
+	num(&c.Cache.Size, "cache-size", "CACHE_SIZE", "entries held by the in-process cache")
+	dur(&c.Cache.TTL, "cache-ttl", "CACHE_TTL", "time entries stay cached, 0 for ever")
+	return env
+}
+
+// Validate - check settings make sense together
+// Every problem found is reported at once
+func (c Config) Validate() error {
+	problems := make([]string, 0)
+	check := func(ok bool, problem string, args ...interface{}) {
+		if !ok {
+			problems = append(problems, fmt.Sprintf(problem, args...))
+		}
+	}
+
+	check(c.Port != "", "port is required")
+	check(c.HTTP.ReadTimeout >= 0 && c.HTTP.WriteTimeout >= 0 && c.HTTP.IdleTimeout >= 0,
+		"http timeouts cannot be negative")
+	check(c.HTTP.ShutdownTimeout > 0, "http shutdown_timeout must be positive")
+	check(c.Limits.MaxBodyBytes > 0 && c.Limits.MaxQueryLength > 0, "limits max_body_bytes and max_query_length must be positive")
+	check(c.Limits.RequestsPerMinute >= 0, "limits requests_per_minute cannot be negative")
+	check(c.Limits.Burst > 0 || c.Limits.RequestsPerMinute == 0, "limits burst must be positive")
+	switch c.Log.Level {
+	case "debug", "info", "warn", "error":
+	default:
+		check(false, "unknown log level %q", c.Log.Level)
+	}
+	check(c.Log.Format == "json" || c.Log.Format == "console", "unknown log format %q", c.Log.Format)
+	switch c.Tracing.Exporter {
+	case "otlp", "stdout", "none":
+	default:
+		check(false, "unknown tracing exporter %q", c.Tracing.Exporter)
+	}
+	check(len(c.Tokenizer.Dictionaries) > 0, "tokenizer dictionaries are required")
+	for _, d := range c.Tokenizer.Dictionaries {
+		check(d == "ipadic" || d == "unidic", "unknown tokenizer dictionary %q", d)
+	}
+	switch c.Tokenizer.Mode {
+	case "normal", "search", "extended":
+	default:
+		check(false, "unknown tokenizer mode %q", c.Tokenizer.Mode)
+	}
+	switch c.Dictionary.Backend {
+	case "mongodb":
+		check(c.MongoDB.URI != "", "mongodb uri is required for the mongodb backend")
+		check(c.MongoDB.Database != "" && c.MongoDB.Collection != "", "mongodb database and collection are required")
+		check(c.MongoDB.MaxPoolSize >= 0 && c.MongoDB.MaxPoolSize <= 65535, "mongodb max_pool_size must be 0 to 65535")
+		check(c.MongoDB.Timeout >= 0, "mongodb timeout cannot be negative")
+		check(c.Dictionary.Version != "", "dictionary version is required")
+		switch c.Cache.Type {
+		case "redis", "tiered":
+			check(c.Redis.URL != "", "redis url is required for the %s cache", c.Cache.Type)
+			check(c.Redis.MaxIdle >= 0 && c.Redis.MaxActive >= 0, "redis pool sizes cannot be negative")
+			check(c.Redis.CommandTimeout > 0, "redis command_timeout must be positive")
+		case "lru", "none":
+		default:
+			check(false, "unknown cache %q", c.Cache.Type)
+		}
+		check(c.Cache.Size > 0 || (c.Cache.Type != "lru" && c.Cache.Type != "tiered"),
+			"cache size must be positive for the %s cache", c.Cache.Type)
+		check(c.Cache.TTL >= 0, "cache ttl cannot be negative")
+	case "memory":
+		check(c.Dictionary.File != "", "dictionary file is required for the memory backend")
+	default:
+		check(false, "unknown dictionary backend %q", c.Dictionary.Backend)
+	}
+
+	if len(problems) > 0 {
+		return fmt.Errorf("invalid config: %s", strings.Join(problems, "; "))
+	}
+	return nil
+}
+
+// stringList - flag.Value for a comma separated list
+// Setting it replaces the list rather than adding to it
+type stringList []string
+
+func (l *stringList) String() string {
+	if l == nil {
+		return ""
+	}
+	return strings.Join(*l, ",")
+}
+
+func (l *stringList) Set(v string) error {
+	*l = make([]string, 0)
+	for _, s := range strings.Split(v, ",") {
+		if s = strings.TrimSpace(s); s != "" {
+			*l = append(*l, s)
+		}
+	}
+	return nil
+}
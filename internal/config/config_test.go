@@ -0,0 +1,127 @@
+package config
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// setenv - set key for the rest of the test
+func setenv(t *testing.T, key, value string) {
+	old, ok := os.LookupEnv(key)
+	os.Setenv(key, value)
+	t.Cleanup(func() {
+		if ok {
+			os.Setenv(key, old)
+		} else {
+			os.Unsetenv(key)
+		}
+	})
+}
+
+func writeFile(t *testing.T, contents string) string {
+	dir, err := ioutil.TempDir("", "config")
+	assert.Nil(t, err)
+	t.Cleanup(func() { os.RemoveAll(dir) })
+	path := filepath.Join(dir, "seibiki.yaml")
+	assert.Nil(t, ioutil.WriteFile(path, []byte(contents), 0644))
+	return path
+}
+
+func TestLoad(t *testing.T) {
+	t.Run("defaults", func(t *testing.T) {
+		setenv(t, "MONGODB_CONNECTION_STRING", "mongodb://localhost")
+		setenv(t, "REDIS_URL", "redis://localhost")
+		c, err := Load(nil)
+		assert.Nil(t, err)
+		assert.Equal(t, "3001", c.Port)
+		assert.Equal(t, "jedict", c.MongoDB.Database)
+		assert.Equal(t, "entries", c.MongoDB.Collection)
+		assert.Equal(t, 24*time.Hour, c.Cache.TTL)
+	})
+
+	t.Run("file, then env, then flags", func(t *testing.T) {
+		path := writeFile(t, `
+port: "4000"
+wwwroot: ./web/build
+mongodb:
+  uri: mongodb://file
+  database: dict
+  timeout: 5s
+cache:
+  type: lru
+  size: 50
+`)
+		setenv(t, "CONFIG_FILE", path)
+		setenv(t, "MONGODB_DATABASE", "env")
+		setenv(t, "CACHE_SIZE", "60")
+		c, err := Load([]string{"-cache-size", "70"})
+		assert.Nil(t, err)
+		assert.Equal(t, "4000", c.Port)
+		assert.Equal(t, "./web/build", c.WWWRoot)
+		assert.Equal(t, "mongodb://file", c.MongoDB.URI)
+		assert.Equal(t, "env", c.MongoDB.Database)
+		assert.Equal(t, "entries", c.MongoDB.Collection)
+		assert.Equal(t, 5*time.Second, c.MongoDB.Timeout)
+		assert.Equal(t, 70, c.Cache.Size)
+	})
+
+	t.Run("lists", func(t *testing.T) {
+		path := writeFile(t, `
+mongodb:
+  uri: mongodb://file
+cache:
+  type: none
+limits:
+  api_keys: [a, b]
+`)
+		setenv(t, "TOKENIZER_DICTIONARIES", "unidic, ipadic")
+		setenv(t, "TOKENIZER_USER_DICTIONARY", "/data/userdic.csv")
+		c, err := Load([]string{"-config", path, "-trust-proxy"})
+		assert.Nil(t, err)
+		assert.Equal(t, []string{"unidic", "ipadic"}, c.Tokenizer.Dictionaries)
+		assert.Equal(t, "/data/userdic.csv", c.Tokenizer.UserDictionary)
+		assert.Equal(t, []string{"a", "b"}, c.Limits.APIKeys)
+		assert.True(t, c.Limits.TrustProxy)
+	})
+
+	t.Run("unknown file key", func(t *testing.T) {
+		_, err := Load([]string{"-config", writeFile(t, "prot: 4000\n")})
+		assert.NotNil(t, err)
+	})
+
+	t.Run("invalid env", func(t *testing.T) {
+		setenv(t, "CACHE_TTL", "a day")
+		_, err := Load(nil)
+		assert.Contains(t, err.Error(), "CACHE_TTL")
+	})
+}
+
+func TestValidate(t *testing.T) {
+	c := Default()
+	c.Cache.Type = "tiered"
+	c.Cache.Size = 0
+	err := c.Validate()
+	assert.Contains(t, err.Error(), "mongodb uri is required")
+	assert.Contains(t, err.Error(), "redis url is required for the tiered cache")
+	assert.Contains(t, err.Error(), "cache size must be positive")
+
+	c = Default()
+	c.Dictionary.Backend = "memory"
+	c.Dictionary.File = "JMdict_e.gz"
+	assert.Nil(t, c.Validate())
+
+	c.Tokenizer.Dictionaries = []string{"jumandic"}
+	c.Limits.MaxQueryLength = 0
+	err = c.Validate()
+	assert.Contains(t, err.Error(), `unknown tokenizer dictionary "jumandic"`)
+	assert.Contains(t, err.Error(), "max_query_length must be positive")
+
+	c = Default()
+	c.Dictionary.Backend = "sqlite"
+	assert.Contains(t, c.Validate().Error(), `unknown dictionary backend "sqlite"`)
+}
@@ -0,0 +1,35 @@
+package mongodb
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/go-kit/kit/metrics"
+)
+
+// instrumented - times the queries of the Client it wraps
+type instrumented struct {
+	Client
+	latency metrics.Histogram
+}
+
+// NewInstrumented - Client timing Get and Search in latency,
+// labelled by method and whether the query failed
+func NewInstrumented(c Client, latency metrics.Histogram) Client {
+	return &instrumented{Client: c, latency: latency}
+}
+
+func (c *instrumented) Get(ctx context.Context, query interface{}) (res []byte, err error) {
+	defer c.observe("get", time.Now(), &err)
+	return c.Client.Get(ctx, query)
+}
+
+func (c *instrumented) Search(ctx context.Context, query interface{}, skip, limit, maxTotal int64) (res []byte, total int64, err error) {
+	defer c.observe("search", time.Now(), &err)
+	return c.Client.Search(ctx, query, skip, limit, maxTotal)
+}
+
+func (c *instrumented) observe(method string, begin time.Time, err *error) {
+	c.latency.With("method", method, "error", fmt.Sprint(*err != nil)).Observe(time.Since(begin).Seconds())
+}
@@ -3,39 +3,112 @@ package mongodb
 import (
 	"context"
 	"encoding/json"
+	"errors"
+	"time"
 
+	"github.com/gilmoreg/seibiki/internal/logging"
 	"github.com/mongodb/mongo-go-driver/bson"
 	"github.com/mongodb/mongo-go-driver/mongo"
 	"github.com/mongodb/mongo-go-driver/mongo/options"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
 	"go.uber.org/zap"
 )
 
+var tracer = otel.Tracer("github.com/gilmoreg/seibiki/internal/connectors/mongodb")
+
 // Client -
 type Client interface {
-	Get(query interface{}) ([]byte, error)
+	Get(ctx context.Context, query interface{}) ([]byte, error)
+	Search(ctx context.Context, query interface{}, skip, limit, maxTotal int64) ([]byte, int64, error)
+	Ping(ctx context.Context) error
+	Disconnect(ctx context.Context) error
+}
+
+// Writer - Client that can also write to the entries collection
+type Writer interface {
+	Client
+	EnsureIndexes(ctx context.Context) error
+	Upsert(ctx context.Context, upserts []Upsert) (UpsertResult, error)
+}
+
+// Upsert - replace the document matching Filter with Document,
+// inserting it if nothing matches
+type Upsert struct {
+	Filter   interface{}
+	Document interface{}
+}
+
+// UpsertResult - counts of documents written by Upsert
+type UpsertResult struct {
+	Matched  int64
+	Modified int64
+	Upserted int64
+}
+
+// Options - where entries live and how to connect
+// Zero values fall back to the jedict database, the entries collection
+// and the driver's pool size and timeouts
+type Options struct {
+	Database    string
+	Collection  string
+	MaxPoolSize uint16
+	// Timeout - limit on connecting, selecting a server and each socket read
+	Timeout time.Duration
 }
 
 type client struct {
-	client *mongo.Client
-	logger *zap.SugaredLogger
+	client  *mongo.Client
+	options Options
+	logger  *zap.SugaredLogger
 }
 
 // New - create new mongodb client
-func New(connectionString string, logger *zap.SugaredLogger) (Client, error) {
-	c := client{logger: logger}
+func New(connectionString string, opts Options, logger *zap.SugaredLogger) (Client, error) {
+	c := client{options: opts.withDefaults(), logger: logger}
 	err := c.connect(connectionString)
 	return &c, err
 }
 
+// NewWriter - create new mongodb client with write access
+func NewWriter(connectionString string, opts Options, logger *zap.SugaredLogger) (Writer, error) {
+	c := client{options: opts.withDefaults(), logger: logger}
+	err := c.connect(connectionString)
+	return &c, err
+}
+
+func (o Options) withDefaults() Options {
+	if o.Database == "" {
+		o.Database = "jedict"
+	}
+	if o.Collection == "" {
+		o.Collection = "entries"
+	}
+	return o
+}
+
 func (m *client) connect(connectionString string) error {
-	client, err := mongo.Connect(context.TODO(), connectionString)
+	opts := options.Client()
+	if m.options.MaxPoolSize > 0 {
+		opts.SetMaxPoolSize(m.options.MaxPoolSize)
+	}
+	ctx := context.TODO()
+	if t := m.options.Timeout; t > 0 {
+		opts.SetConnectTimeout(t).SetServerSelectionTimeout(t).SetSocketTimeout(t)
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, t)
+		defer cancel()
+	}
+	client, err := mongo.Connect(ctx, connectionString, opts)
 	if err != nil {
 		m.logger.Error(err)
 		return err
 	}
 
 	// Check the connection
-	err = client.Ping(context.TODO(), nil)
+	err = client.Ping(ctx, nil)
 	if err != nil {
 		m.logger.Error(err)
 		return err
@@ -46,25 +119,138 @@ func (m *client) connect(connectionString string) error {
 }
 
 // Get - perform a dictionary lookup
-func (m *client) Get(query interface{}) ([]byte, error) {
-	cur, err := m.client.Database("jedict").Collection("entries").Find(context.TODO(), query, options.Find())
+func (m *client) Get(ctx context.Context, query interface{}) ([]byte, error) {
+	return m.find(ctx, query, options.Find())
+}
+
+// Search - one page of the documents matching query, in sequence order,
+// along with how many match in total, counting no more than maxTotal
+func (m *client) Search(ctx context.Context, query interface{}, skip, limit, maxTotal int64) ([]byte, int64, error) {
+	total, err := m.entries().CountDocuments(ctx, query, options.Count().SetLimit(maxTotal))
 	if err != nil {
-		m.logger.Error(err)
+		m.log(ctx).Error(err)
+		return nil, 0, err
+	}
+	opts := options.Find().
+		SetSort(bson.M{"sequence": 1}).
+		SetSkip(skip).
+		SetLimit(limit)
+	res, err := m.find(ctx, query, opts)
+	return res, total, err
+}
+
+// find - documents matching query as a JSON array
+func (m *client) find(ctx context.Context, query interface{}, opts *options.FindOptions) (res []byte, err error) {
+	ctx, span := tracer.Start(ctx, "mongodb.Find", trace.WithSpanKind(trace.SpanKindClient))
+	span.SetAttributes(
+		attribute.String("db.system", "mongodb"),
+		attribute.String("db.name", m.options.Database),
+		attribute.String("db.mongodb.collection", m.options.Collection),
+		attribute.String("db.operation", "find"),
+	)
+	defer func() {
+		if err != nil {
+			span.RecordError(err)
+			span.SetStatus(codes.Error, err.Error())
+		}
+		span.End()
+	}()
+
+	cur, err := m.entries().Find(ctx, query, opts)
+	if err != nil {
+		m.log(ctx).Error(err)
 		return nil, err
 	}
-	defer cur.Close(context.TODO())
+	defer cur.Close(context.Background())
 
 	var result bson.A
 
-	for cur.Next(context.TODO()) {
+	for cur.Next(ctx) {
 		var elem bson.M
 		err := cur.Decode(&elem)
 		if err != nil {
-			m.logger.Warn(err)
+			m.log(ctx).Warn(err)
 			continue
 		}
 
 		result = append(result, elem)
 	}
+	if err := cur.Err(); err != nil {
+		m.log(ctx).Error(err)
+		return nil, err
+	}
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
 	return json.Marshal(result)
 }
+
+// EnsureIndexes - create the indexes lookups, searches and upserts rely on
+// Glosses are indexed without a language, so words are neither stemmed
+// nor dropped as stop words
+func (m *client) EnsureIndexes(ctx context.Context) error {
+	_, err := m.entries().Indexes().CreateMany(ctx, []mongo.IndexModel{
+		{Keys: bson.M{"sequence": 1}, Options: options.Index().SetUnique(true)},
+		{Keys: bson.M{"kanji": 1}},
+		{Keys: bson.M{"readings": 1}},
+		{Keys: bson.M{"meanings.gloss": "text"}, Options: options.Index().SetDefaultLanguage("none")},
+	})
+	if err != nil {
+		m.log(ctx).Error(err)
+	}
+	return err
+}
+
+// Upsert - write documents in a single unordered bulk write
+func (m *client) Upsert(ctx context.Context, upserts []Upsert) (UpsertResult, error) {
+	if len(upserts) == 0 {
+		return UpsertResult{}, nil
+	}
+	models := make([]mongo.WriteModel, len(upserts))
+	for i, u := range upserts {
+		models[i] = mongo.NewReplaceOneModel().
+			SetFilter(u.Filter).
+			SetReplacement(u.Document).
+			SetUpsert(true)
+	}
+	res, err := m.entries().BulkWrite(ctx, models, options.BulkWrite().SetOrdered(false))
+	if err != nil {
+		m.log(ctx).Error(err)
+		return UpsertResult{}, err
+	}
+	return UpsertResult{
+		Matched:  res.MatchedCount,
+		Modified: res.ModifiedCount,
+		Upserted: res.UpsertedCount,
+	}, nil
+}
+
+// Ping - check the primary can be reached
+func (m *client) Ping(ctx context.Context) error {
+	if m.client == nil {
+		return errors.New("not connected")
+	}
+	return m.client.Ping(ctx, nil)
+}
+
+// Disconnect - close every pooled connection, waiting for
+// operations in progress until ctx is done
+func (m *client) Disconnect(ctx context.Context) error {
+	if m.client == nil {
+		return nil
+	}
+	err := m.client.Disconnect(ctx)
+	if err != nil {
+		m.log(ctx).Error(err)
+	}
+	return err
+}
+
+// log - logger tagged with the request ID in ctx
+func (m *client) log(ctx context.Context) *zap.SugaredLogger {
+	return logging.FromContext(ctx, m.logger)
+}
+
+func (m *client) entries() *mongo.Collection {
+	return m.client.Database(m.options.Database).Collection(m.options.Collection)
+}
@@ -3,29 +3,58 @@
 package redis
 
 import (
-	"errors"
+	"context"
+	"strings"
 	"time"
 
+	"github.com/gilmoreg/seibiki/internal/logging"
 	"github.com/gomodule/redigo/redis"
 	"go.uber.org/zap"
 )
 
 // Client - interface for cache client
 type Client interface {
-	Get(key string) ([]byte, error)
-	Set(key string, value []byte) error
+	Get(ctx context.Context, key string) ([]byte, error)
+	MGet(ctx context.Context, keys []string) ([][]byte, error)
+	Set(ctx context.Context, key string, value []byte, ttl time.Duration) error
+	DeletePrefix(ctx context.Context, prefix string) (int, error)
+	Ping(ctx context.Context) error
+	Close() error
 }
 
 type redisClient struct {
-	pool   *redis.Pool
-	logger *zap.SugaredLogger
+	pool           *redis.Pool
+	commandTimeout time.Duration
+	logger         *zap.SugaredLogger
+}
+
+// Options - connection pool settings
+// Zero MaxIdle, IdleTimeout and CommandTimeout fall back to 3, 240s and 5s;
+// zero MaxActive is unlimited
+type Options struct {
+	MaxIdle     int
+	MaxActive   int
+	IdleTimeout time.Duration
+	// CommandTimeout - longest a command may wait for its reply,
+	// whatever the deadline of its context
+	CommandTimeout time.Duration
 }
 
 // New - return new redisClient
-func New(connectionString string, l *zap.SugaredLogger) Client {
+func New(connectionString string, opts Options, l *zap.SugaredLogger) Client {
+	if opts.MaxIdle == 0 {
+		opts.MaxIdle = 3
+	}
+	if opts.IdleTimeout == 0 {
+		opts.IdleTimeout = 240 * time.Second
+	}
+	if opts.CommandTimeout == 0 {
+		opts.CommandTimeout = 5 * time.Second
+	}
 	p := &redis.Pool{
-		MaxIdle:     3,
-		IdleTimeout: 240 * time.Second,
+		MaxIdle:     opts.MaxIdle,
+		MaxActive:   opts.MaxActive,
+		IdleTimeout: opts.IdleTimeout,
 		Dial: func() (redis.Conn, error) {
 			c, err := redis.DialURL(connectionString)
 			if err != nil {
@@ -39,48 +68,150 @@ func New(connectionString string, l *zap.SugaredLogger) Client {
 		},
 	}
 
-	return &redisClient{pool: p, logger: l}
+	return &redisClient{pool: p, commandTimeout: opts.CommandTimeout, logger: l}
 }
 
 // Ping - ping server
-func (c redisClient) Ping() error {
-	conn := c.pool.Get()
-	defer conn.Close()
-
-	_, err := redis.String(conn.Do("PING"))
+func (c redisClient) Ping(ctx context.Context) error {
+	_, err := redis.String(c.do(ctx, "PING"))
 	if err != nil {
-		c.logger.Error(err)
+		c.log(ctx).Error(err)
 		return err
 	}
 	return nil
 }
 
+// Close - close the pool and its idle connections
+// Connections in use are closed as they are returned
+func (c redisClient) Close() error {
+	return c.pool.Close()
+}
+
 // Get - get from Redis
-func (c redisClient) Get(key string) ([]byte, error) {
-	conn := c.pool.Get()
-	defer conn.Close()
+func (c redisClient) Get(ctx context.Context, key string) ([]byte, error) {
+	return redis.Bytes(c.do(ctx, "GET", key))
+}
+
+// MGet - get several keys from Redis in one round trip
+// Missing keys are returned as nil
+func (c redisClient) MGet(ctx context.Context, keys []string) ([][]byte, error) {
+	args := make([]interface{}, len(keys))
+	for i, key := range keys {
+		args[i] = key
+	}
+	return redis.ByteSlices(c.do(ctx, "MGET", args...))
+}
 
-	var data []byte
-	data, err := redis.Bytes(conn.Do("GET", key))
+// Set - set in Redis, expiring after ttl unless ttl is 0
+func (c redisClient) Set(ctx context.Context, key string, value []byte, ttl time.Duration) error {
+	var err error
+	if ttl > 0 {
+		_, err = c.do(ctx, "SET", key, value, "PX", int64(ttl/time.Millisecond))
+	} else {
+		_, err = c.do(ctx, "SET", key, value)
+	}
 	if err != nil {
-		return data, err
+		c.log(ctx).Errorf("error setting %s: %s", key, err.Error())
 	}
-	return data, err
+	return err
 }
 
-// Set - set in Redis
-func (c redisClient) Set(key string, value []byte) error {
-	conn := c.pool.Get()
-	defer conn.Close()
+// DeletePrefix - delete every key starting with prefix
+// Uses SCAN rather than KEYS so the server is not blocked
+func (c redisClient) DeletePrefix(ctx context.Context, prefix string) (int, error) {
+	deleted := 0
+	cursor := "0"
+	for {
+		res, err := redis.Values(c.do(ctx, "SCAN", cursor, "MATCH", escapeGlob(prefix)+"*", "COUNT", 1000))
+		if err != nil {
+			c.log(ctx).Error(err)
+			return deleted, err
+		}
+		var keys []string
+		_, err = redis.Scan(res, &cursor, &keys)
+		if err != nil {
+			return deleted, err
+		}
+		if len(keys) > 0 {
+			args := make([]interface{}, len(keys))
+			for i, key := range keys {
+				args[i] = key
+			}
+			n, err := redis.Int(c.do(ctx, "DEL", args...))
+			if err != nil {
+				c.log(ctx).Error(err)
+				return deleted, err
+			}
+			deleted += n
+		}
+		if cursor == "0" {
+			return deleted, nil
+		}
+	}
+}
 
-	_, err := conn.Do("SET", key, value)
-	if err != nil {
-		v := string(value)
-		if len(v) > 15 {
-			v = v[0:12] + "..."
+// log - logger tagged with the request ID in ctx
+func (c redisClient) log(ctx context.Context) *zap.SugaredLogger {
+	return logging.FromContext(ctx, c.logger)
+}
+
+// escapeGlob - escape characters SCAN MATCH treats as a pattern
+func escapeGlob(s string) string {
+	var b strings.Builder
+	for _, r := range s {
+		switch r {
+		case '*', '?', '[', ']', '\\':
+			b.WriteRune('\\')
 		}
-		c.logger.Error(v)
-		return errors.New(v)
+		b.WriteRune(r)
 	}
-	return err
+	return b.String()
+}
+
+// do - run a command on a connection from the pool, giving up when ctx
+// is done or its deadline passes
+// Redis cannot abandon a command it has been sent, so once ctx is done
+// the caller stops waiting and the connection goes back to the pool when
+// the reply arrives, or is dropped once the command times out
+func (c redisClient) do(ctx context.Context, cmd string, args ...interface{}) (interface{}, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	conn, err := c.pool.GetContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+	type result struct {
+		reply interface{}
+		err   error
+	}
+	done := make(chan result, 1)
+	go func() {
+		defer conn.Close()
+		reply, err := doWithTimeout(ctx, conn, c.commandTimeout, cmd, args...)
+		done <- result{reply, err}
+	}()
+	select {
+	case r := <-done:
+		return r.reply, r.err
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+}
+
+// doWithTimeout - run a command, timing out after max or at ctx's
+// deadline, whichever is sooner
+// A timed out connection is closed rather than reused by the pool
+func doWithTimeout(ctx context.Context, conn redis.Conn, max time.Duration, cmd string, args ...interface{}) (interface{}, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	timeout := max
+	if deadline, ok := ctx.Deadline(); ok && time.Until(deadline) < timeout {
+		timeout = time.Until(deadline)
+	}
+	if timeout <= 0 {
+		return nil, context.DeadlineExceeded
+	}
+	return redis.DoWithTimeout(conn, timeout, cmd, args...)
 }
@@ -1,51 +1,136 @@
 package redis
 
 import (
+	"context"
+	"net"
 	"testing"
+	"time"
 
+	"github.com/gomodule/redigo/redis"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 	"go.uber.org/zap"
 )
 
 func TestRedisDriver(t *testing.T) {
-	client := New("redis://localhost:6379", newTestLogger())
+	client := New("redis://localhost:6379", Options{}, newTestLogger())
 
 	t.Run("PING", func(t *testing.T) {
-		err := client.(*redisClient).Ping()
+		err := client.Ping(context.Background())
 		assert.Nil(t, err)
 	})
 
 	t.Run("SET", func(t *testing.T) {
-		err := client.Set("test", []byte("test"))
+		err := client.Set(context.Background(), "test", []byte("test"), 0)
 		assert.Nil(t, err)
 	})
 
 	t.Run("GET", func(t *testing.T) {
-		res, err := client.Get("test")
+		res, err := client.Get(context.Background(), "test")
 		assert.Nil(t, err)
 		assert.Equal(t, "test", string(res))
 	})
+
+	t.Run("MGET", func(t *testing.T) {
+		res, err := client.MGet(context.Background(), []string{"test", "missing-key"})
+		require.NoError(t, err)
+		require.Len(t, res, 2)
+		assert.Equal(t, "test", string(res[0]))
+		assert.Nil(t, res[1])
+	})
+
+	t.Run("SET with TTL", func(t *testing.T) {
+		err := client.Set(context.Background(), "test:ttl", []byte("test"), time.Millisecond)
+		assert.Nil(t, err)
+		time.Sleep(10 * time.Millisecond)
+		_, err = client.Get(context.Background(), "test:ttl")
+		assert.Equal(t, redis.ErrNil, err)
+	})
+
+	t.Run("DeletePrefix", func(t *testing.T) {
+		client.Set(context.Background(), "test:prefix:a", []byte("a"), 0)
+		client.Set(context.Background(), "test:prefix:b", []byte("b"), 0)
+		n, err := client.DeletePrefix(context.Background(), "test:prefix:")
+		assert.Nil(t, err)
+		assert.Equal(t, 2, n)
+	})
 }
 
 func TestRedisDriverErrors(t *testing.T) {
-	client := New("redis://nowhere", newTestLogger())
+	client := New("redis://nowhere", Options{}, newTestLogger())
 
 	t.Run("PING error", func(t *testing.T) {
-		err := client.(*redisClient).Ping()
+		err := client.Ping(context.Background())
 		assert.NotNil(t, err)
 	})
 
 	t.Run("SET error", func(t *testing.T) {
-		err := client.Set("test", []byte("test"))
+		err := client.Set(context.Background(), "test", []byte("test"), 0)
 		assert.NotNil(t, err)
 	})
 
 	t.Run("GET error", func(t *testing.T) {
-		_, err := client.Get("test")
+		_, err := client.Get(context.Background(), "test")
+		assert.NotNil(t, err)
+	})
+
+	t.Run("MGET error", func(t *testing.T) {
+		_, err := client.MGet(context.Background(), []string{"test"})
 		assert.NotNil(t, err)
 	})
 }
 
+func TestRedisDriverContext(t *testing.T) {
+	client := New("redis://localhost:6379", Options{}, newTestLogger())
+
+	t.Run("GET cancelled", func(t *testing.T) {
+		ctx, cancel := context.WithCancel(context.Background())
+		cancel()
+		_, err := client.Get(ctx, "test")
+		assert.Equal(t, context.Canceled, err)
+	})
+}
+
+func TestRedisDriverCancel(t *testing.T) {
+	client := New("redis://"+silentServer(t), Options{}, newTestLogger())
+	defer client.Close()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	time.AfterFunc(20*time.Millisecond, cancel)
+	begin := time.Now()
+	_, err := client.Get(ctx, "test")
+	assert.Equal(t, context.Canceled, err)
+	assert.True(t, time.Since(begin) < time.Second)
+}
+
+func TestRedisDriverCommandTimeout(t *testing.T) {
+	client := New("redis://"+silentServer(t), Options{CommandTimeout: 20 * time.Millisecond}, newTestLogger())
+	defer client.Close()
+
+	begin := time.Now()
+	_, err := client.Get(context.Background(), "test")
+	assert.NotNil(t, err)
+	assert.True(t, time.Since(begin) < time.Second)
+}
+
+// silentServer - address of a server that accepts connections but
+// never replies, closed when t ends
+func silentServer(t *testing.T) string {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	t.Cleanup(func() { l.Close() })
+	go func() {
+		for {
+			conn, err := l.Accept()
+			if err != nil {
+				return
+			}
+			t.Cleanup(func() { conn.Close() })
+		}
+	}()
+	return l.Addr().String()
+}
+
 func newTestLogger() *zap.SugaredLogger {
 	return zap.NewExample().Sugar()
 }
@@ -0,0 +1,231 @@
+package dictionary
+
+import (
+	"strings"
+	"unicode/utf8"
+)
+
+// Rule-based deinflection in the style of rikaichan/Yomichan.
+// Each rule strips one inflection from the end of a word, so chaining them
+// walks 食べなかった → 食べない → 食べる.
+
+// wordType - bit set of the conjugation classes a word form can belong to
+type wordType uint
+
+const (
+	typeV1      wordType = 1 << iota // ichidan verb
+	typeV5                           // godan verb
+	typeVK                           // kuru verb
+	typeVS                           // suru verb, or noun taking する
+	typeAdjI                         // i-adjective, including ない/たい forms
+	typeMasu                         // polite ます form
+	typeTe                           // te form, as left behind by ちゃう/とく/ている
+	typeInitial                      // the word as written, before any rule
+	typeAll     wordType = 1<<iota - 1
+)
+
+// typeFinal - types a dictionary form can have
+const typeFinal = typeV1 | typeV5 | typeVK | typeVS | typeAdjI
+
+// Deinflection - a possible dictionary form of an inflected word
+type Deinflection struct {
+	Word string
+	// Inflection - reasons for each rule applied, outermost first
+	// e.g. ["past", "negative"] for 食べなかった → 食べる
+	Inflection []string
+	types      wordType
+}
+
+// Matches - whether an entry's meaning can be this form's part of speech
+func (d Deinflection) Matches(m Meaning) bool {
+	if d.types&typeFinal == typeFinal {
+		return true
+	}
+	for _, pos := range m.PartOfSpeech {
+		if d.types&posType(pos) != 0 {
+			return true
+		}
+	}
+	return false
+}
+
+// posType - conjugation class of an EDict part of speech code
+func posType(pos string) wordType {
+	switch {
+	case pos == "&v1;" || pos == "&v1-s;":
+		return typeV1
+	case strings.HasPrefix(pos, "&v5"):
+		return typeV5
+	case pos == "&vk;":
+		return typeVK
+	case strings.HasPrefix(pos, "&vs") || pos == "&vz;":
+		return typeVS
+	case pos == "&adj-i;" || pos == "&adj-ix;":
+		return typeAdjI
+	}
+	return 0
+}
+
+// Deinflect - candidate dictionary forms of word, fewest rules first
+// The word itself is not included
+func Deinflect(word string) []Deinflection {
+	candidates := []Deinflection{{Word: word, types: typeAll}}
+	seen := map[string]int{word: 0}
+	for i := 0; i < len(candidates); i++ {
+		current := candidates[i]
+		for _, rule := range deinflectRules {
+			if current.types&rule.in == 0 || !strings.HasSuffix(current.Word, rule.from) {
+				continue
+			}
+			next := strings.TrimSuffix(current.Word, rule.from) + rule.to
+			if utf8.RuneCountInString(next) < 2 {
+				continue
+			}
+			if n, ok := seen[next]; ok {
+				// Same form reached another way - it may belong to either class
+				candidates[n].types |= rule.out
+				continue
+			}
+			inflection := current.Inflection
+			// The te form left behind by ちゃう etc. is not worth reporting
+			if rule.reason != "" && current.types != typeTe {
+				inflection = append(append([]string{}, current.Inflection...), rule.reason)
+			}
+			seen[next] = len(candidates)
+			candidates = append(candidates, Deinflection{
+				Word:       next,
+				Inflection: inflection,
+				types:      rule.out,
+			})
+		}
+	}
+	return candidates[1:]
+}
+
+type deinflectRule struct {
+	from   string
+	to     string
+	in     wordType // the word must be able to be one of these
+	out    wordType // what the word becomes
+	reason string
+}
+
+var deinflectRules = buildRules()
+
+// godanRows - the endings of each godan verb class
+var godanRows = []struct {
+	dict, a, i, e, o, te, ta string
+}{
+	{"う", "わ", "い", "え", "お", "って", "った"},
+	{"く", "か", "き", "け", "こ", "いて", "いた"},
+	{"ぐ", "が", "ぎ", "げ", "ご", "いで", "いだ"},
+	{"す", "さ", "し", "せ", "そ", "して", "した"},
+	{"つ", "た", "ち", "て", "と", "って", "った"},
+	{"ぬ", "な", "に", "ね", "の", "んで", "んだ"},
+	{"ぶ", "ば", "び", "べ", "ぼ", "んで", "んだ"},
+	{"む", "ま", "み", "め", "も", "んで", "んだ"},
+	{"る", "ら", "り", "れ", "ろ", "って", "った"},
+}
+
+func buildRules() []deinflectRule {
+	rules := make([]deinflectRule, 0)
+	add := func(from, to string, in, out wordType, reason string) {
+		rules = append(rules, deinflectRule{from, to, in, out, reason})
+	}
+
+	// Forms shared by every verb class, given the stem each class uses
+	verb := func(a, i, e, o, te, ta, dict string, out wordType) {
+		add(a+"ない", dict, typeAdjI, out, "negative")
+		add(i+"ます", dict, typeMasu, out, "polite")
+		add(i+"たい", dict, typeAdjI, out, "-tai")
+		add(i+"すぎる", dict, typeV1, out, "too much")
+		add(i+"そう", dict, typeInitial, out, "-sou")
+		add(ta, dict, typeInitial, out, "past")
+		add(ta+"ら", dict, typeInitial, out, "-tara")
+		add(ta+"り", dict, typeInitial, out, "-tari")
+		add(te, dict, typeInitial|typeTe, out, "te")
+		add(e+"ば", dict, typeInitial, out, "-ba")
+		add(o, dict, typeInitial, out, "volitional")
+	}
+
+	// Ichidan: 食べる
+	verb("", "", "れ", "よう", "て", "た", "る", typeV1)
+	add("られる", "る", typeV1, typeV1, "potential or passive")
+	add("させる", "る", typeV1, typeV1, "causative")
+	add("ろ", "る", typeInitial, typeV1, "imperative")
+
+	// Godan: 飲む
+	for _, r := range godanRows {
+		verb(r.a, r.i, r.e, r.o+"う", r.te, r.ta, r.dict, typeV5)
+		add(r.e+"る", r.dict, typeV1, typeV5, "potential")
+		add(r.a+"れる", r.dict, typeV1, typeV5, "passive")
+		add(r.a+"せる", r.dict, typeV1, typeV5, "causative")
+		add(r.e, r.dict, typeInitial, typeV5, "imperative")
+	}
+	// 行く is irregular in the te and past forms
+	for _, iku := range []string{"行", "い"} {
+		add(iku+"って", iku+"く", typeInitial|typeTe, typeV5, "te")
+		add(iku+"った", iku+"く", typeInitial, typeV5, "past")
+		add(iku+"ったら", iku+"く", typeInitial, typeV5, "-tara")
+	}
+
+	// Kuru: 来る
+	for _, k := range []struct{ dict, ko, ki, ku string }{
+		{"くる", "こ", "き", "く"},
+		{"来る", "来", "来", "来"},
+	} {
+		verb(k.ko, k.ki, k.ku+"れ", k.ko+"よう", k.ki+"て", k.ki+"た", k.dict, typeVK)
+		add(k.ko+"られる", k.dict, typeV1, typeVK, "potential or passive")
+		add(k.ko+"させる", k.dict, typeV1, typeVK, "causative")
+		add(k.ko+"い", k.dict, typeInitial, typeVK, "imperative")
+	}
+
+	// Suru: する, and nouns that take it
+	verb("し", "し", "すれ", "しよう", "して", "した", "する", typeVS)
+	add("される", "する", typeV1, typeVS, "passive")
+	add("させる", "する", typeV1, typeVS, "causative")
+	add("できる", "する", typeV1, typeVS, "potential")
+	add("しろ", "する", typeInitial, typeVS, "imperative")
+	add("せよ", "する", typeInitial, typeVS, "imperative")
+	add("する", "", typeVS, typeVS, "")
+
+	// I-adjectives: 寒い
+	add("くない", "い", typeAdjI, typeAdjI, "negative")
+	add("かった", "い", typeInitial, typeAdjI, "past")
+	add("かったら", "い", typeInitial, typeAdjI, "-tara")
+	add("かったり", "い", typeInitial, typeAdjI, "-tari")
+	add("くて", "い", typeInitial|typeTe, typeAdjI, "te")
+	add("ければ", "い", typeInitial, typeAdjI, "-ba")
+	add("く", "い", typeInitial, typeAdjI, "adverbial")
+	add("さ", "い", typeInitial, typeAdjI, "noun")
+	add("そう", "い", typeInitial, typeAdjI, "-sou")
+	add("すぎる", "い", typeV1, typeAdjI, "too much")
+
+	// Polite forms, back to ます
+	add("ました", "ます", typeInitial, typeMasu, "past")
+	add("ません", "ます", typeInitial, typeMasu, "negative")
+	add("ませんでした", "ます", typeInitial, typeMasu, "negative past")
+	add("ましょう", "ます", typeInitial, typeMasu, "volitional")
+	add("まして", "ます", typeInitial, typeMasu, "te")
+
+	// Auxiliaries attached to the te form
+	add("ちゃう", "て", typeV5, typeTe, "-chau")
+	add("じゃう", "で", typeV5, typeTe, "-chau")
+	add("ちまう", "て", typeV5, typeTe, "-chau")
+	add("じまう", "で", typeV5, typeTe, "-chau")
+	add("てしまう", "て", typeV5, typeTe, "-te shimau")
+	add("でしまう", "で", typeV5, typeTe, "-te shimau")
+	add("とく", "て", typeV5, typeTe, "-toku")
+	add("どく", "で", typeV5, typeTe, "-toku")
+	add("ておく", "て", typeV5, typeTe, "-te oku")
+	add("でおく", "で", typeV5, typeTe, "-te oku")
+	add("ている", "て", typeV1, typeTe, "-te iru")
+	add("でいる", "で", typeV1, typeTe, "-te iru")
+	add("てる", "て", typeV1, typeTe, "-te iru")
+	add("でる", "で", typeV1, typeTe, "-te iru")
+
+	// Sentence-final じゃん attaches to any plain form
+	add("じゃん", "", typeInitial, typeAll, "-jan")
+
+	return rules
+}
@@ -0,0 +1,96 @@
+package dictionary
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDeinflect(t *testing.T) {
+	tests := []struct {
+		word       string
+		expected   string
+		inflection []string
+	}{
+		{"食べなかった", "食べる", []string{"past", "negative"}},
+		{"飲んじゃった", "飲む", []string{"past", "-chau"}},
+		{"書いとく", "書く", []string{"-toku"}},
+		{"行った", "行く", []string{"past"}},
+		{"寒くなかった", "寒い", []string{"past", "negative"}},
+		{"勉強しました", "勉強", []string{"past", "polite"}},
+		{"来られる", "来る", []string{"potential or passive"}},
+		{"飲ませられた", "飲む", []string{"past", "potential or passive", "causative"}},
+		{"うまいじゃん", "うまい", []string{"-jan"}},
+	}
+	for _, test := range tests {
+		t.Run(test.word, func(t *testing.T) {
+			var found *Deinflection
+			for _, d := range Deinflect(test.word) {
+				if d.Word == test.expected {
+					found = &d
+					break
+				}
+			}
+			if assert.NotNil(t, found) {
+				assert.Equal(t, test.inflection, found.Inflection)
+			}
+		})
+	}
+}
+
+func TestDeinflectionMatches(t *testing.T) {
+	d := deinflection(t, "食べた", "食べる")
+	assert.True(t, d.Matches(Meaning{PartOfSpeech: []string{"&v1;", "&vt;"}}))
+	assert.False(t, d.Matches(Meaning{PartOfSpeech: []string{"&n;"}}))
+}
+
+func TestSetDeinflectedEntries(t *testing.T) {
+	w := NewWord([]Token{
+		{Class: "UNKNOWN", Surface: "ググ", POS: []string{"名詞", "一般", "*", "*"}, Base: "*"},
+		{Surface: "っ", POS: []string{"動詞", "非自立", "*", "*"}, Base: "っ"},
+		{Surface: "た", POS: []string{"助動詞", "*", "*", "*"}, Base: "た", Entries: []Entry{{Sequence: 2}}},
+	})
+	assert.Contains(t, w.DeinflectionCandidates(), "ググる")
+
+	w = w.SetDeinflectedEntries(map[string][]Entry{
+		"ググる": []Entry{{Sequence: 1, Meanings: []Meaning{{Gloss: "to google", PartOfSpeech: []string{"&v5r;"}}}}},
+	})
+	assert.Equal(t, 1, w.Tokens[0].Entries[0].Sequence)
+	assert.Equal(t, "ググる", w.Tokens[0].Deinflected)
+	assert.Equal(t, []string{"past"}, w.Tokens[0].Inflection)
+	assert.Equal(t, 2, w.Tokens[2].Entries[0].Sequence)
+}
+
+func TestDeinflectionCandidatesCapped(t *testing.T) {
+	missing := func(surface string) Token {
+		return Token{Class: "UNKNOWN", Surface: surface, POS: []string{"動詞", "自立", "*", "*"}, Base: "*"}
+	}
+	w := NewWord([]Token{missing("食べさせられなかった"), missing("させられなかった"), missing("られなかった")})
+	candidates := w.DeinflectionCandidates()
+	assert.True(t, len(candidates) <= 3*maxTokenCandidates)
+	assert.Equal(t, "食べさせられなかったさせられなかったられなかっる", candidates[0])
+	assert.Equal(t, "させられなかったられなかっる", candidates[maxTokenCandidates])
+	assert.Contains(t, candidates, "させられる")
+
+	words := make([]Word, 0)
+	for r := 'ぁ'; r <= 'ゖ'; r++ {
+		words = append(words, NewWord([]Token{missing(string(r) + "べさせられなかった")}))
+	}
+	candidates = DeinflectionCandidates(words)
+	assert.Len(t, candidates, maxCandidates)
+	seen := make(map[string]bool)
+	for _, candidate := range candidates {
+		assert.False(t, seen[candidate], candidate)
+		seen[candidate] = true
+	}
+}
+
+func deinflection(t *testing.T, word, expected string) Deinflection {
+	for _, d := range Deinflect(word) {
+		if d.Word == expected {
+			return d
+		}
+	}
+	t.Fatalf("%s does not deinflect to %s", word, expected)
+	return Deinflection{}
+}
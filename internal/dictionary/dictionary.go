@@ -1,44 +1,87 @@
 package dictionary
 
 import (
+	"context"
 	"encoding/json"
-	"log"
+	"fmt"
+	"sync"
 
+	"github.com/gilmoreg/seibiki/internal/cache"
 	"github.com/gilmoreg/seibiki/internal/connectors/mongodb"
-	"github.com/gilmoreg/seibiki/internal/connectors/redis"
-	redigo "github.com/gomodule/redigo/redis"
+	"github.com/gilmoreg/seibiki/internal/logging"
 	"github.com/mongodb/mongo-go-driver/bson"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
 	"go.uber.org/zap"
 )
 
+var tracer = otel.Tracer("github.com/gilmoreg/seibiki/internal/dictionary")
+
 // Repository - repository for dictionary
 type Repository interface {
-	Lookup(query string) ([]Entry, error)
+	Lookup(ctx context.Context, query string) ([]Entry, error)
+	LookupMany(ctx context.Context, queries []string) (map[string][]Entry, error)
 }
 
 type dictionary struct {
 	db     mongodb.Client
-	cache  redis.Client
+	cache  cache.Cache
 	logger *zap.SugaredLogger
+	// fills - cache writes still running after their lookup returned
+	fills sync.WaitGroup
+	// mu guards closing, which stops new fills once Close has started
+	mu      sync.Mutex
+	closing bool
+}
+
+// Invalidator - Repository whose cached entries can be dropped,
+// e.g. after the dictionary data changes
+type Invalidator interface {
+	Invalidate(ctx context.Context) (int, error)
+}
+
+// Pinger - Repository backed by a server that can be checked for readiness
+type Pinger interface {
+	Ping(ctx context.Context) error
+}
+
+// Closer - Repository holding connections or background work
+// that should be finished before the process exits
+type Closer interface {
+	Close(ctx context.Context) error
 }
 
 // New - new Dictionary Repository
-func New(db mongodb.Client, cache redis.Client, logger *zap.SugaredLogger) Repository {
+// Entries are cached under a key namespace that includes version,
+// so bumping it after a re-import never serves stale entries.
+// A failing cache is logged and skipped; lookups fall back to the db
+func New(db mongodb.Client, c cache.Cache, version string, logger *zap.SugaredLogger) Repository {
 	return &dictionary{
 		db:     db,
-		cache:  cache,
+		cache:  cache.NewNamespace(c, CacheKeyPrefix(version)),
 		logger: logger,
 	}
 }
 
+// CacheKeyPrefix - namespace for cached entries of a dictionary version
+// Keys look like seibiki:v{version}:entry:{base}
+func CacheKeyPrefix(version string) string {
+	return fmt.Sprintf("seibiki:v%s:entry:", version)
+}
+
 // Lookup - find entries from cache or db
-func (d *dictionary) Lookup(query string) ([]Entry, error) {
-	ok, cached, err := d.cacheLookup(query)
+func (d *dictionary) Lookup(ctx context.Context, query string) (entries []Entry, err error) {
+	ctx, span := tracer.Start(ctx, "dictionary.Lookup")
+	defer func() { endSpan(span, err) }()
+	ok, cached, err := d.cacheLookup(ctx, query)
+	span.SetAttributes(attribute.Bool("cache.hit", ok))
 	if ok {
 		return cached, nil
 	}
 	if err != nil {
-		d.logger.Error(err)
+		d.log(ctx).Error(err)
 		return nil, err
 	}
 	pipeline := bson.M{
@@ -47,51 +90,254 @@ func (d *dictionary) Lookup(query string) ([]Entry, error) {
 			bson.M{"kanji": query},
 		},
 	}
-	rawEntries, err := d.db.Get(pipeline)
+	rawEntries, err := d.db.Get(ctx, pipeline)
 	if err != nil {
-		d.logger.Error(err)
+		d.log(ctx).Error(err)
 		return nil, err
 	}
-	entries, err := decode(rawEntries)
+	entries, err = decode(rawEntries)
 	if err != nil {
-		d.logger.Error(err)
+		d.log(ctx).Error(err)
 		return nil, err
 	}
-	go d.cacheFill(query, entries)
+	d.fill(ctx, func(ctx context.Context, log *zap.SugaredLogger) {
+		d.cacheFill(ctx, log, query, entries)
+	})
 	return entries, nil
 }
 
-func (d *dictionary) cacheLookup(query string) (bool, []Entry, error) {
-	// d.logger.Infof("Checking cache for %s", query)
-	data, err := d.cache.Get(query)
-	if err == redigo.ErrNil {
-		// d.logger.Infof("%s does not exist in cache. Fetching from db", query)
+// LookupMany - find entries for several queries at once
+// Cache hits are fetched with a single MGET and misses with a single db query
+// If the db fails, the cache hits are returned along with the error
+func (d *dictionary) LookupMany(ctx context.Context, queries []string) (result map[string][]Entry, err error) {
+	ctx, span := tracer.Start(ctx, "dictionary.LookupMany")
+	defer func() { endSpan(span, err) }()
+	queries = dedupe(queries)
+	span.SetAttributes(attribute.Int("queries", len(queries)))
+	result = make(map[string][]Entry, len(queries))
+	if len(queries) == 0 {
+		return result, nil
+	}
+	misses, err := d.cacheLookupMany(ctx, queries, result)
+	if err != nil {
+		d.log(ctx).Error(err)
+		return nil, err
+	}
+	span.SetAttributes(attribute.Int("cache.hits", len(queries)-len(misses)))
+	if len(misses) == 0 {
+		return result, nil
+	}
+	pipeline := bson.M{
+		"$or": bson.A{
+			bson.M{"readings": bson.M{"$in": misses}},
+			bson.M{"kanji": bson.M{"$in": misses}},
+		},
+	}
+	rawEntries, err := d.db.Get(ctx, pipeline)
+	if err != nil {
+		d.log(ctx).Error(err)
+		return result, err
+	}
+	entries, err := decode(rawEntries)
+	if err != nil {
+		d.log(ctx).Error(err)
+		return result, err
+	}
+	found := group(misses, entries)
+	for query, e := range found {
+		result[query] = e
+	}
+	d.fill(ctx, func(ctx context.Context, log *zap.SugaredLogger) {
+		d.cacheFillMany(ctx, log, found)
+	})
+	return result, nil
+}
+
+// Invalidate - drop every cached entry for this dictionary version
+func (d *dictionary) Invalidate(ctx context.Context) (int, error) {
+	n, err := d.cache.Flush(ctx, "")
+	if err != nil {
+		d.log(ctx).Error(err)
+		return n, err
+	}
+	d.log(ctx).Infof("flushed %d cached entries", n)
+	return n, nil
+}
+
+// Ping - check the db can be reached
+// The cache is not checked; lookups work without it
+func (d *dictionary) Ping(ctx context.Context) error {
+	return d.db.Ping(ctx)
+}
+
+// Close - wait for pending cache writes, then disconnect from the db
+// Gives up waiting when ctx is done; the db is disconnected either way
+// Lookups still running are answered, but no longer cached
+func (d *dictionary) Close(ctx context.Context) error {
+	d.mu.Lock()
+	d.closing = true
+	d.mu.Unlock()
+	done := make(chan struct{})
+	go func() {
+		d.fills.Wait()
+		close(done)
+	}()
+	var err error
+	select {
+	case <-done:
+	case <-ctx.Done():
+		err = ctx.Err()
+		d.log(ctx).Warnf("abandoning pending cache writes: %s", err.Error())
+	}
+	if dbErr := d.db.Disconnect(ctx); dbErr != nil {
+		return dbErr
+	}
+	return err
+}
+
+// fill - run a cache write in the background, detached from the
+// request in ctx, unless Close has started
+func (d *dictionary) fill(ctx context.Context, write func(ctx context.Context, log *zap.SugaredLogger)) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if d.closing {
+		d.log(ctx).Debug("closing, not caching lookup")
+		return
+	}
+	d.fills.Add(1)
+	go func(ctx context.Context, log *zap.SugaredLogger) {
+		defer d.fills.Done()
+		write(ctx, log)
+	}(detach(ctx), d.log(ctx))
+}
+
+func (d *dictionary) cacheLookup(ctx context.Context, query string) (bool, []Entry, error) {
+	data, err := d.cache.Get(ctx, query)
+	if err == cache.ErrMiss {
+		d.log(ctx).Debugf("%s is not cached, fetching from db", query)
 		return false, nil, nil
 	}
 	if err != nil {
-		d.logger.Errorf("Error connecting to cache: %s", err.Error())
-		return false, nil, err
+		if ctxErr := ctx.Err(); ctxErr != nil {
+			return false, nil, ctxErr
+		}
+		d.log(ctx).Warnf("cache unavailable, falling back to db: %s", err.Error())
+		return false, nil, nil
 	}
 	var entries []Entry
 	err = json.Unmarshal(data, &entries)
 	if err != nil {
-		d.logger.Error(err)
-		return false, nil, err
+		d.log(ctx).Warnf("discarding unreadable cache entry for %s: %s", query, err.Error())
+		return false, nil, nil
 	}
-	// d.logger.Infof("fetched %s", query)
+	d.log(ctx).Debugf("fetched %s from cache", query)
 	return true, entries, nil
 }
 
-func (d *dictionary) cacheFill(query string, entries []Entry) {
+// cacheLookupMany - add cache hits to result and return the queries that missed
+// If the cache fails, every query it could not answer counts as a miss
+func (d *dictionary) cacheLookupMany(ctx context.Context, queries []string, result map[string][]Entry) ([]string, error) {
+	data, err := d.cache.MGet(ctx, queries)
+	if err != nil {
+		if ctxErr := ctx.Err(); ctxErr != nil {
+			return nil, ctxErr
+		}
+		d.log(ctx).Warnf("cache unavailable, falling back to db: %s", err.Error())
+	}
+	misses := make([]string, 0)
+	for i, query := range queries {
+		if i >= len(data) || data[i] == nil {
+			misses = append(misses, query)
+			continue
+		}
+		var entries []Entry
+		err = json.Unmarshal(data[i], &entries)
+		if err != nil {
+			d.log(ctx).Warnf("discarding unreadable cache entry for %s: %s", query, err.Error())
+			misses = append(misses, query)
+			continue
+		}
+		result[query] = entries
+	}
+	return misses, nil
+}
+
+// cacheFill - cache entries for query, logging to the logger of
+// the request that looked them up
+func (d *dictionary) cacheFill(ctx context.Context, log *zap.SugaredLogger, query string, entries []Entry) {
 	bytes, err := json.Marshal(&entries)
 	if err != nil {
-		log.Fatal(err)
+		log.Errorf("error encoding entries for %s: %s", query, err.Error())
+		return
+	}
+	log.Debugf("setting %s in cache", query)
+	err = d.cache.Set(ctx, query, bytes)
+	if err != nil {
+		log.Errorf("error setting cache: %s", err.Error())
 	}
-	// d.logger.Infof("setting %s in cache", query)
-	err = d.cache.Set(query, bytes)
+}
+
+func (d *dictionary) cacheFillMany(ctx context.Context, log *zap.SugaredLogger, found map[string][]Entry) {
+	for query, entries := range found {
+		d.cacheFill(ctx, log, query, entries)
+	}
+}
+
+// detach - context for cache writes that outlive the request
+// The request may be finished by the time they run, so they are not
+// tied to its cancellation, but they stay in its trace
+func detach(ctx context.Context) context.Context {
+	return trace.ContextWithSpanContext(context.Background(), trace.SpanContextFromContext(ctx))
+}
+
+// endSpan - end span, marking it failed if err is set
+func endSpan(span trace.Span, err error) {
 	if err != nil {
-		d.logger.Errorf("error setting cache: %s", err.Error())
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+	}
+	span.End()
+}
+
+// log - logger tagged with the request ID in ctx
+func (d *dictionary) log(ctx context.Context) *zap.SugaredLogger {
+	return logging.FromContext(ctx, d.logger)
+}
+
+// group - assign each entry to the queries matching its kanji or readings
+// Every query is present in the result, even if nothing matched,
+// so that empty results are cached as well
+func group(queries []string, entries []Entry) map[string][]Entry {
+	result := make(map[string][]Entry, len(queries))
+	for _, query := range queries {
+		result[query] = nil
+	}
+	for _, entry := range entries {
+		matched := make(map[string]bool)
+		for _, forms := range [][]string{entry.Kanji, entry.Readings} {
+			for _, form := range forms {
+				if _, ok := result[form]; ok && !matched[form] {
+					matched[form] = true
+					result[form] = append(result[form], entry)
+				}
+			}
+		}
+	}
+	return result
+}
+
+// dedupe - remove duplicate and empty queries, preserving order
+func dedupe(queries []string) []string {
+	seen := make(map[string]bool, len(queries))
+	result := make([]string, 0, len(queries))
+	for _, query := range queries {
+		if query == "" || seen[query] {
+			continue
+		}
+		seen[query] = true
+		result = append(result, query)
 	}
+	return result
 }
 
 // decode - convert bson.A to []Entry
@@ -0,0 +1,269 @@
+package dictionary
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/gilmoreg/seibiki/internal/cache"
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/zap"
+)
+
+func TestLookupMany(t *testing.T) {
+	t.Run("batches misses into one db query", func(t *testing.T) {
+		db := &fakeDB{entries: []Entry{
+			{Sequence: 1, Kanji: []string{"飲む"}, Readings: []string{"のむ"}},
+			{Sequence: 2, Kanji: []string{"寒い"}, Readings: []string{"さむい"}},
+		}}
+		d := New(db, newFakeCache(), "1", zap.NewNop().Sugar())
+		res, err := d.LookupMany(context.Background(), []string{"飲む", "寒い", "飲む", "ない"})
+		assert.Nil(t, err)
+		assert.Equal(t, 1, db.calls)
+		assert.Equal(t, 3, len(res))
+		assert.Equal(t, 1, res["飲む"][0].Sequence)
+		assert.Equal(t, 2, res["寒い"][0].Sequence)
+		assert.Empty(t, res["ない"])
+	})
+
+	t.Run("skips db when every query is cached", func(t *testing.T) {
+		db := &fakeDB{}
+		c := newFakeCache()
+		put(c, "seibiki:v1:entry:飲む", []Entry{{Sequence: 1}})
+		d := New(db, c, "1", zap.NewNop().Sugar())
+		res, err := d.LookupMany(context.Background(), []string{"飲む"})
+		assert.Nil(t, err)
+		assert.Equal(t, 0, db.calls)
+		assert.Equal(t, 1, res["飲む"][0].Sequence)
+	})
+
+	t.Run("empty queries", func(t *testing.T) {
+		db := &fakeDB{}
+		d := New(db, newFakeCache(), "1", zap.NewNop().Sugar())
+		res, err := d.LookupMany(context.Background(), []string{})
+		assert.Nil(t, err)
+		assert.Empty(t, res)
+		assert.Equal(t, 0, db.calls)
+	})
+
+	t.Run("db error", func(t *testing.T) {
+		db := &fakeDB{err: errors.New("db down")}
+		d := New(db, newFakeCache(), "1", zap.NewNop().Sugar())
+		_, err := d.LookupMany(context.Background(), []string{"飲む"})
+		assert.NotNil(t, err)
+	})
+
+	t.Run("falls back to db when cache is down", func(t *testing.T) {
+		db := &fakeDB{entries: []Entry{{Sequence: 1, Kanji: []string{"飲む"}}}}
+		d := New(db, brokenCache{}, "1", zap.NewNop().Sugar())
+		res, err := d.LookupMany(context.Background(), []string{"飲む"})
+		assert.Nil(t, err)
+		assert.Equal(t, 1, res["飲む"][0].Sequence)
+		entries, err := d.Lookup(context.Background(), "飲む")
+		assert.Nil(t, err)
+		assert.Equal(t, 1, entries[0].Sequence)
+	})
+
+	t.Run("cancelled context", func(t *testing.T) {
+		d := New(&fakeDB{}, newFakeCache(), "1", zap.NewNop().Sugar())
+		ctx, cancel := context.WithCancel(context.Background())
+		cancel()
+		_, err := d.LookupMany(ctx, []string{"飲む"})
+		assert.Equal(t, context.Canceled, err)
+	})
+}
+
+func TestInvalidate(t *testing.T) {
+	c := newFakeCache()
+	put(c, "seibiki:v1:entry:飲む", []Entry{{Sequence: 1}})
+	put(c, "seibiki:v2:entry:飲む", []Entry{{Sequence: 2}})
+	db := &fakeDB{}
+	d := New(db, c, "1", zap.NewNop().Sugar())
+
+	n, err := d.(Invalidator).Invalidate(context.Background())
+	assert.Nil(t, err)
+	assert.Equal(t, 1, n)
+	_, err = d.Lookup(context.Background(), "飲む")
+	assert.Nil(t, err)
+	assert.Equal(t, 1, db.calls)
+	_, err = c.Get(context.Background(), "seibiki:v2:entry:飲む")
+	assert.Nil(t, err)
+}
+
+func TestClose(t *testing.T) {
+	t.Run("waits for cache writes", func(t *testing.T) {
+		c := newFakeCache()
+		db := &fakeDB{entries: []Entry{{Sequence: 1, Kanji: []string{"飲む"}}}}
+		d := New(db, slowCache{Cache: c, delay: 20 * time.Millisecond}, "1", zap.NewNop().Sugar())
+		_, err := d.LookupMany(context.Background(), []string{"飲む"})
+		assert.Nil(t, err)
+
+		err = d.(Closer).Close(context.Background())
+		assert.Nil(t, err)
+		assert.True(t, db.closed)
+		_, err = c.Get(context.Background(), "seibiki:v1:entry:飲む")
+		assert.Nil(t, err)
+	})
+
+	t.Run("gives up when ctx is done", func(t *testing.T) {
+		db := &fakeDB{entries: []Entry{{Sequence: 1, Kanji: []string{"飲む"}}}}
+		d := New(db, slowCache{Cache: newFakeCache(), delay: time.Second}, "1", zap.NewNop().Sugar())
+		d.Lookup(context.Background(), "飲む")
+
+		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+		defer cancel()
+		err := d.(Closer).Close(ctx)
+		assert.Equal(t, context.DeadlineExceeded, err)
+		assert.True(t, db.closed)
+	})
+
+	t.Run("lookups during Close", func(t *testing.T) {
+		db := &fakeDB{entries: []Entry{{Sequence: 1, Kanji: []string{"飲む"}}}}
+		c := &lateCache{Cache: newFakeCache()}
+		d := New(db, c, "1", zap.NewNop().Sugar())
+
+		stop := make(chan struct{})
+		var wg sync.WaitGroup
+		for i := 0; i < 8; i++ {
+			wg.Add(1)
+			go func(i int) {
+				defer wg.Done()
+				for j := 0; ; j++ {
+					select {
+					case <-stop:
+						return
+					default:
+					}
+					query := fmt.Sprintf("%d-%d", i, j)
+					d.Lookup(context.Background(), query)
+					d.LookupMany(context.Background(), []string{query + "x"})
+				}
+			}(i)
+		}
+		time.Sleep(5 * time.Millisecond)
+		assert.Nil(t, d.(Closer).Close(context.Background()))
+		c.close()
+		time.Sleep(5 * time.Millisecond)
+		close(stop)
+		wg.Wait()
+		// Fills that started before Close are waited for, none start after
+		assert.Equal(t, int32(0), atomic.LoadInt32(&c.late))
+	})
+}
+
+func TestGroup(t *testing.T) {
+	entries := []Entry{
+		{Sequence: 1, Kanji: []string{"上手"}, Readings: []string{"じょうず", "うわて"}},
+		{Sequence: 2, Readings: []string{"うわて"}},
+	}
+	res := group([]string{"上手", "うわて", "下手"}, entries)
+	assert.Equal(t, 1, len(res["上手"]))
+	assert.Equal(t, 2, len(res["うわて"]))
+	_, ok := res["下手"]
+	assert.True(t, ok)
+}
+
+type fakeDB struct {
+	mu      sync.Mutex
+	entries []Entry
+	err     error
+	calls   int
+	query   interface{}
+	closed  bool
+}
+
+func (f *fakeDB) Get(ctx context.Context, query interface{}) ([]byte, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.calls++
+	if f.err != nil {
+		return nil, f.err
+	}
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	return json.Marshal(f.entries)
+}
+
+func (f *fakeDB) Search(ctx context.Context, query interface{}, skip, limit, maxTotal int64) ([]byte, int64, error) {
+	f.calls++
+	f.query = query
+	if f.err != nil {
+		return nil, 0, f.err
+	}
+	b, err := json.Marshal(f.entries)
+	return b, int64(len(f.entries)), err
+}
+
+func (f *fakeDB) Ping(ctx context.Context) error {
+	return f.err
+}
+
+func (f *fakeDB) Disconnect(ctx context.Context) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.closed = true
+	return nil
+}
+
+// slowCache - cache whose writes take delay
+type slowCache struct {
+	cache.Cache
+	delay time.Duration
+}
+
+func (c slowCache) Set(ctx context.Context, key string, value []byte) error {
+	time.Sleep(c.delay)
+	return c.Cache.Set(ctx, key, value)
+}
+
+// lateCache - cache counting writes made after close
+type lateCache struct {
+	cache.Cache
+	closed int32
+	late   int32
+}
+
+func (c *lateCache) close() {
+	atomic.StoreInt32(&c.closed, 1)
+}
+
+func (c *lateCache) Set(ctx context.Context, key string, value []byte) error {
+	if atomic.LoadInt32(&c.closed) == 1 {
+		atomic.AddInt32(&c.late, 1)
+	}
+	return c.Cache.Set(ctx, key, value)
+}
+
+// brokenCache - cache whose server is unreachable
+type brokenCache struct{}
+
+func (brokenCache) Get(ctx context.Context, key string) ([]byte, error) {
+	return nil, errors.New("connection refused")
+}
+
+func (brokenCache) MGet(ctx context.Context, keys []string) ([][]byte, error) {
+	return nil, errors.New("connection refused")
+}
+
+func (brokenCache) Set(ctx context.Context, key string, value []byte) error {
+	return errors.New("connection refused")
+}
+
+func (brokenCache) Flush(ctx context.Context, prefix string) (int, error) {
+	return 0, errors.New("connection refused")
+}
+
+func newFakeCache() cache.Cache {
+	return cache.NewLRU(100, 0)
+}
+
+func put(c cache.Cache, key string, entries []Entry) {
+	b, _ := json.Marshal(entries)
+	c.Set(context.Background(), key, b)
+}
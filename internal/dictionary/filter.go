@@ -5,6 +5,7 @@ import (
 )
 
 // Filter returns a slice of meanings that are deemed relevant
+// to the IPA part of speech
 func Filter(pos []string, meanings []Meaning) []Meaning {
 	res := make([]Meaning, 0)
 	for _, m := range meanings {
@@ -15,31 +16,96 @@ func Filter(pos []string, meanings []Meaning) []Meaning {
 	return res
 }
 
+// Annotate returns a copy of meanings, each marked with whether
+// it matches the IPA part of speech and why
+func Annotate(pos []string, meanings []Meaning) []Meaning {
+	return annotate(ipaToEdictMapping, pos, meanings)
+}
+
+// annotate - Annotate with the EDict mapping for the dictionary pos is from
+func annotate(mapping map[string][]string, pos []string, meanings []Meaning) []Meaning {
+	res := make([]Meaning, 0, len(meanings))
+	for _, m := range meanings {
+		result := check(mapping, pos, m)
+		m.Match = &result
+		res = append(res, m)
+	}
+	return res
+}
+
 // match compares the IPA part of speech tags to JEDict codes
 // to see if this entry matches the token in context
 func match(pos []string, meaning Meaning) bool {
+	return check(ipaToEdictMapping, pos, meaning).Matched
+}
+
+// check - match against the EDict codes mapping gives pos,
+// with the reason for the decision
+func check(mapping map[string][]string, pos []string, meaning Meaning) MeaningMatch {
 	// If something went wrong with pos, just return everything
 	// Better to show something than nothing
 	if len(pos) < 1 {
-		return true
+		return MeaningMatch{Matched: true, Reason: "token has no part of speech"}
 	}
 
 	partOfSpeech := strings.Join(pos, ",")
 
-	if edictTypes, ok := ipaToEdictMapping[partOfSpeech]; ok {
-		for _, edict := range meaning.PartOfSpeech {
-			if in(edict, edictTypes) {
-				return true
-			}
-		}
-	} else {
-		// If it doesn't match any IPA type,
+	edictTypes, ok := mapping[partOfSpeech]
+	if !ok {
+		// If it doesn't match any IPA or UniDic type,
 		// do not include (may change)
-		return false
+		return MeaningMatch{Reason: "no EDict mapping for " + partOfSpeech}
+	}
+	for _, edict := range meaning.PartOfSpeech {
+		if in(edict, edictTypes) {
+			return MeaningMatch{Matched: true, Reason: edict + " fits " + partOfSpeech}
+		}
+	}
+	return MeaningMatch{Reason: strings.Join(meaning.PartOfSpeech, " ") + " does not fit " + partOfSpeech}
+}
+
+// POSLabel - t's part of speech as a metrics label: one its dictionary's
+// EDict mapping knows, "none" or "other"
+// Keeps the label's values bounded, as user dictionary words can have any
+func (t Token) POSLabel() string {
+	if len(t.POS) == 0 {
+		return "none"
+	}
+	pos := strings.Join(t.POS, ",")
+	if _, ok := t.SysDic.edictMapping()[pos]; !ok {
+		return "other"
+	}
+	return pos
+}
+
+// Strictness - what happens to meanings that do not match
+// their token's part of speech
+type Strictness string
+
+const (
+	// StrictnessStrict - drop them, and entries left with no meanings,
+	// and keep only entries with the token's reading if there are any
+	StrictnessStrict Strictness = "strict"
+	// StrictnessRanked - keep them, after the meanings and entries that match
+	StrictnessRanked Strictness = "ranked"
+	// StrictnessOff - keep them in dictionary order
+	StrictnessOff Strictness = "off"
+)
+
+// Valid - true if s is a supported Strictness
+func (s Strictness) Valid() bool {
+	switch s {
+	case StrictnessStrict, StrictnessRanked, StrictnessOff:
+		return true
 	}
 	return false
 }
 
+// matched - true if m was not checked or matched its part of speech
+func (m Meaning) matched() bool {
+	return m.Match == nil || m.Match.Matched
+}
+
 func in(pos string, posTypes []string) bool {
 	for _, t := range posTypes {
 		if pos == t {
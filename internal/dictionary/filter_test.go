@@ -61,3 +61,68 @@ func TestFilter(t *testing.T) {
 func meanings(pos string) []Meaning {
 	return []Meaning{Meaning{PartOfSpeech: []string{pos}}}
 }
+
+func TestAnnotate(t *testing.T) {
+	pos := []string{"名詞", "一般", "*", "*"}
+	res := Annotate(pos, append(meanings("&n;"), meanings("&v5m;")...))
+	assert.Equal(t, &MeaningMatch{Matched: true, Reason: "&n; fits 名詞,一般,*,*"}, res[0].Match)
+	assert.False(t, res[1].Match.Matched)
+	assert.Equal(t, "&v5m; does not fit 名詞,一般,*,*", res[1].Match.Reason)
+
+	res = Annotate([]string{"", "", "", ""}, meanings("&n;"))
+	assert.Equal(t, "no EDict mapping for ,,,", res[0].Match.Reason)
+}
+
+func TestPOSLabel(t *testing.T) {
+	assert.Equal(t, "名詞,一般,*,*", Token{POS: []string{"名詞", "一般", "*", "*"}}.POSLabel())
+	assert.Equal(t, "名詞,普通名詞,一般,*", Token{POS: []string{"名詞", "普通名詞", "一般", "*"}, SysDic: SysDicUni}.POSLabel())
+	assert.Equal(t, "other", Token{POS: []string{"名詞", "普通名詞", "一般", "*"}}.POSLabel(), "UniDic's, on an IPADIC token")
+	assert.Equal(t, "other", Token{POS: []string{"カスタム名詞"}}.POSLabel())
+	assert.Equal(t, "none", Token{}.POSLabel())
+}
+
+func TestApplyStrictness(t *testing.T) {
+	token := Token{Surface: "寒い", POS: []string{"形容詞", "自立", "*", "*"}}.SetEntries([]Entry{
+		{Sequence: 1, Meanings: meanings("&n;")},
+		{Sequence: 2, Meanings: append(meanings("&n;"), meanings("&adj-i;")...)},
+	})
+	sequences := func(t Token) []int {
+		res := make([]int, 0)
+		for _, e := range t.Entries {
+			res = append(res, e.Sequence)
+		}
+		return res
+	}
+
+	strict := token.ApplyStrictness(StrictnessStrict)
+	assert.Equal(t, []int{2}, sequences(strict))
+	assert.Equal(t, 1, len(strict.Entries[0].Meanings))
+
+	ranked := token.ApplyStrictness(StrictnessRanked)
+	assert.Equal(t, []int{2, 1}, sequences(ranked))
+	assert.Equal(t, []string{"&adj-i;"}, ranked.Entries[0].Meanings[0].PartOfSpeech)
+
+	off := token.ApplyStrictness(StrictnessOff)
+	assert.Equal(t, []int{1, 2}, sequences(off))
+	assert.Equal(t, []string{"&n;"}, off.Entries[1].Meanings[0].PartOfSpeech)
+}
+
+func TestApplyStrictnessPhrase(t *testing.T) {
+	words := JoinPhrases(tokenize(t, "気にしないで"), map[string][]Entry{
+		"気にする": {
+			{Sequence: 2, Kanji: []string{"気にする"}, Readings: []string{"けにする"}, Meanings: meanings("&exp;")},
+			{Sequence: 1, Kanji: []string{"気にする"}, Readings: []string{"きにする"}, Meanings: meanings("&exp;"), Priority: []string{"ichi1"}},
+		},
+	})
+	sequences := func(w Word) []int {
+		res := make([]int, 0)
+		for _, e := range w.Entries {
+			res = append(res, e.Sequence)
+		}
+		return res
+	}
+
+	assert.Equal(t, []int{1}, sequences(words[0].ApplyStrictness(StrictnessStrict)))
+	assert.Equal(t, []int{1, 2}, sequences(words[0].ApplyStrictness(StrictnessRanked)))
+	assert.Equal(t, []int{2, 1}, sequences(words[0].ApplyStrictness(StrictnessOff)))
+}
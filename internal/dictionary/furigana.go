@@ -0,0 +1,143 @@
+package dictionary
+
+import (
+	"html"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/gilmoreg/seibiki/internal/transliterate"
+)
+
+// Ruby - run of a token's surface with the hiragana shown above it
+// Reading is empty for kana, which need none
+type Ruby struct {
+	Text    string `json:"text"`
+	Reading string `json:"reading,omitempty"`
+}
+
+// ruby - split the token's surface into kana and kanji runs,
+// giving each kanji run its part of the reading
+// Okurigana are matched against the reading, so 取り扱い gives
+// 取(と)り扱(あつか)い. A reading that cannot be aligned is put over
+// the whole surface
+func (t Token) ruby() []Ruby {
+	runs := kanjiRuns(t.Surface)
+	reading := transliterate.ToHiragana(t.Reading)
+	needed := false
+	for _, run := range runs {
+		needed = needed || run.kanji
+	}
+	if !needed || reading == "" || reading == "*" {
+		return []Ruby{{Text: t.Surface}}
+	}
+
+	readings, ok := align(runs, reading)
+	if !ok {
+		return []Ruby{{Text: t.Surface, Reading: reading}}
+	}
+	result := make([]Ruby, len(runs))
+	for i, run := range runs {
+		result[i] = Ruby{Text: run.text}
+		if run.kanji {
+			result[i].Reading = readings[i]
+		}
+	}
+	return result
+}
+
+// align - the part of reading under each of runs, if all of it can be
+// shared out: each kana run must appear in the reading as is, and each
+// kanji run takes at least one character of what lies between, as few
+// as will let the rest align
+func align(runs []run, reading string) ([]string, bool) {
+	if len(runs) == 0 {
+		return nil, reading == ""
+	}
+	if !runs[0].kanji {
+		kana := transliterate.ToHiragana(runs[0].text)
+		if !strings.HasPrefix(reading, kana) {
+			return nil, false
+		}
+		rest, ok := align(runs[1:], reading[len(kana):])
+		return append([]string{""}, rest...), ok
+	}
+	for i := 0; i < len(reading); {
+		_, size := utf8.DecodeRuneInString(reading[i:])
+		i += size
+		if rest, ok := align(runs[1:], reading[i:]); ok {
+			return append([]string{reading[:i]}, rest...), true
+		}
+	}
+	return nil, false
+}
+
+// run - stretch of a surface that is all kana, or all kanji and the like
+type run struct {
+	text  string
+	kanji bool
+}
+
+// kanjiRuns - surface split into runs of kana and of everything else,
+// which needs a reading
+func kanjiRuns(surface string) []run {
+	runs := make([]run, 0)
+	for _, r := range surface {
+		kanji := !isKana(r)
+		if n := len(runs); n > 0 && runs[n-1].kanji == kanji {
+			runs[n-1].text += string(r)
+		} else {
+			runs = append(runs, run{text: string(r), kanji: kanji})
+		}
+	}
+	return runs
+}
+
+func isKana(r rune) bool {
+	return unicode.In(r, unicode.Hiragana, unicode.Katakana) || r == 'ー'
+}
+
+// SetFurigana - attach ruby to each of the Word's tokens
+func (w Word) SetFurigana() Word {
+	newTokens := make([]Token, 0)
+	for _, token := range w.Tokens {
+		if !token.IsPunctuation() {
+			token.Furigana = token.ruby()
+		}
+		newTokens = append(newTokens, token)
+	}
+	w.Tokens = newTokens
+	return w
+}
+
+// SetFurigana - attach ruby to every token in words
+func SetFurigana(words []Word) []Word {
+	result := make([]Word, 0)
+	for _, word := range words {
+		result = append(result, word.SetFurigana())
+	}
+	return result
+}
+
+// RubyHTML - words rendered as HTML with <ruby> markup over each kanji run
+// of the tokens' Furigana, so SetFurigana first; tokens without are plain text
+func RubyHTML(words []Word) string {
+	var b strings.Builder
+	for _, word := range words {
+		for _, token := range word.Tokens {
+			if len(token.Furigana) == 0 {
+				b.WriteString(html.EscapeString(token.Surface))
+				continue
+			}
+			for _, r := range token.Furigana {
+				if r.Reading == "" {
+					b.WriteString(html.EscapeString(r.Text))
+					continue
+				}
+				b.WriteString("<ruby>" + html.EscapeString(r.Text) +
+					"<rp>(</rp><rt>" + html.EscapeString(r.Reading) + "</rt><rp>)</rp></ruby>")
+			}
+		}
+	}
+	return b.String()
+}
@@ -0,0 +1,41 @@
+package dictionary
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRuby(t *testing.T) {
+	tests := []struct {
+		surface, reading string
+		expected         []Ruby
+	}{
+		{"寒い", "サムイ", []Ruby{{"寒", "さむ"}, {"い", ""}}},
+		{"取り扱い", "トリアツカイ", []Ruby{{"取", "と"}, {"り", ""}, {"扱", "あつか"}, {"い", ""}}},
+		{"お茶", "オチャ", []Ruby{{"お", ""}, {"茶", "ちゃ"}}},
+		{"一生懸命", "イッショウケンメイ", []Ruby{{"一生懸命", "いっしょうけんめい"}}},
+		{"ココア", "ココア", []Ruby{{"ココア", ""}}},
+		// no reading to align
+		{"ググ", "", []Ruby{{"ググ", ""}}},
+		// okurigana missing from the reading
+		{"行く", "イッタ", []Ruby{{"行く", "いった"}}},
+		// kana in the reading under a kanji run as well as after it
+		{"受け付け", "ウケツケ", []Ruby{{"受", "う"}, {"け", ""}, {"付", "つ"}, {"け", ""}}},
+		{"見た目", "ミタメ", []Ruby{{"見", "み"}, {"た", ""}, {"目", "め"}}},
+	}
+	for _, test := range tests {
+		t.Run(test.surface, func(t *testing.T) {
+			token := Token{Surface: test.surface, Reading: test.reading, POS: []string{"名詞"}}
+			assert.Equal(t, test.expected, token.ruby())
+		})
+	}
+}
+
+func TestRubyHTML(t *testing.T) {
+	words := SetFurigana(tokenize(t, "寒い。"))
+	assert.Equal(t, []Ruby{{"寒", "さむ"}, {"い", ""}}, words[0].Tokens[0].Furigana)
+	assert.Nil(t, words[1].Tokens[0].Furigana)
+	assert.Equal(t, "<ruby>寒<rp>(</rp><rt>さむ</rt><rp>)</rp></ruby>い。", RubyHTML(words))
+	assert.Equal(t, "寒い。", RubyHTML(tokenize(t, "寒い。")), "without furigana")
+}
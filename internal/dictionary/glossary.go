@@ -0,0 +1,159 @@
+package dictionary
+
+import (
+	"context"
+	"sync"
+)
+
+// Glossary - user-defined entries, e.g. for names and terms JMdict
+// lacks or translates differently than the texts being read need
+// Lookups through WithGlossary return them alongside the dictionary's
+type Glossary struct {
+	mu      sync.RWMutex
+	entries Repository
+	size    int
+}
+
+// NewGlossary - empty Glossary
+func NewGlossary() *Glossary {
+	return &Glossary{entries: NewMemory(nil)}
+}
+
+// Set - replace every entry in the glossary, marking each as User
+func (g *Glossary) Set(entries []Entry) {
+	user := make([]Entry, 0, len(entries))
+	for _, entry := range entries {
+		entry.User = true
+		user = append(user, entry)
+	}
+	repo := NewMemory(user)
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	g.entries, g.size = repo, len(user)
+}
+
+// Len - number of entries in the glossary
+func (g *Glossary) Len() int {
+	g.mu.RLock()
+	defer g.mu.RUnlock()
+	return g.size
+}
+
+func (g *Glossary) repo() Repository {
+	g.mu.RLock()
+	defer g.mu.RUnlock()
+	return g.entries
+}
+
+// searcher - the glossary's entries, which are always in memory
+func (g *Glossary) searcher() Searcher {
+	return g.repo().(Searcher)
+}
+
+// withGlossary - Repository adding glossary entries to every lookup
+type withGlossary struct {
+	Repository
+	glossary *Glossary
+}
+
+// WithGlossary - Repository returning the entries g has for a query
+// along with r's
+// Only Lookup and LookupMany are wrapped; type assert on r, not the
+// result, for Searcher, Invalidator and the like
+func WithGlossary(r Repository, g *Glossary) Repository {
+	return &withGlossary{Repository: r, glossary: g}
+}
+
+func (w *withGlossary) Lookup(ctx context.Context, query string) ([]Entry, error) {
+	entries, err := w.Repository.Lookup(ctx, query)
+	if err != nil {
+		return entries, err
+	}
+	user, err := w.glossary.repo().Lookup(ctx, query)
+	return append(entries, user...), err
+}
+
+// LookupMany - r's entries for queries, plus the glossary's
+// If r fails, whatever it found is still returned with the glossary's
+func (w *withGlossary) LookupMany(ctx context.Context, queries []string) (map[string][]Entry, error) {
+	result, err := w.Repository.LookupMany(ctx, queries)
+	if result == nil {
+		return nil, err
+	}
+	user, userErr := w.glossary.repo().LookupMany(ctx, queries)
+	for query, entries := range user {
+		if len(entries) > 0 {
+			result[query] = append(result[query], entries...)
+		}
+	}
+	if err == nil {
+		err = userErr
+	}
+	return result, err
+}
+
+// searchWithGlossary - Searcher adding glossary entries to every search
+type searchWithGlossary struct {
+	Searcher
+	glossary *Glossary
+}
+
+// SearchWithGlossary - Searcher returning the entries g matches before s's,
+// and g's entry for a sequence number in place of s's
+func SearchWithGlossary(s Searcher, g *Glossary) Searcher {
+	return &searchWithGlossary{Searcher: s, glossary: g}
+}
+
+// Search - a page of the glossary's matches followed by s's
+// Every glossary match is found for each page, as there are few
+func (w *searchWithGlossary) Search(ctx context.Context, q SearchQuery) (SearchResult, error) {
+	result := SearchResult{Offset: q.Offset, Limit: q.Limit, Entries: make([]Entry, 0)}
+	all := q
+	all.Offset, all.Limit = 0, MaxSearchTotal
+	user, err := w.glossary.searcher().Search(ctx, all)
+	if err != nil {
+		return result, err
+	}
+	if q.Offset < len(user.Entries) {
+		end := q.Offset + q.Limit
+		if end > len(user.Entries) {
+			end = len(user.Entries)
+		}
+		result.Entries = append(result.Entries, user.Entries[q.Offset:end]...)
+	}
+
+	// s's matches start where the glossary's end; its page is asked
+	// for even when the glossary's fill this one, for the total
+	rest := q
+	rest.Offset -= len(user.Entries)
+	if rest.Offset < 0 {
+		rest.Offset = 0
+	}
+	room := q.Limit - len(result.Entries)
+	rest.Limit = room
+	if rest.Limit < 1 {
+		rest.Limit = 1
+	}
+	dict, err := w.Searcher.Search(ctx, rest)
+	if err != nil {
+		return result, err
+	}
+	if len(dict.Entries) > room {
+		dict.Entries = dict.Entries[:room]
+	}
+	result.Entries = append(result.Entries, dict.Entries...)
+	result.Total = len(user.Entries) + dict.Total
+	if result.Total > MaxSearchTotal {
+		result.Total = MaxSearchTotal
+	}
+	return result, nil
+}
+
+// Entry - the glossary's entry with sequence number, or s's
+func (w *searchWithGlossary) Entry(ctx context.Context, sequence int) (Entry, error) {
+	entry, err := w.glossary.searcher().Entry(ctx, sequence)
+	if err != ErrNotFound {
+		return entry, err
+	}
+	return w.Searcher.Entry(ctx, sequence)
+}
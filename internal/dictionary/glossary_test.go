@@ -0,0 +1,86 @@
+package dictionary
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestGlossary(t *testing.T) {
+	repo := NewMemory([]Entry{
+		{Sequence: 1, Kanji: []string{"上手"}, Readings: []string{"じょうず"}, Meanings: []Meaning{{Gloss: "skill", PartOfSpeech: []string{"&n;"}}}},
+	})
+	g := NewGlossary()
+	r := WithGlossary(repo, g)
+
+	res, err := r.Lookup(context.Background(), "上手")
+	assert.Nil(t, err)
+	assert.Equal(t, 1, len(res))
+
+	g.Set([]Entry{
+		{Kanji: []string{"上手"}, Readings: []string{"かみて"}, Meanings: []Meaning{{Gloss: "stage left"}}},
+		{Sequence: 9000001, Kanji: []string{"朝青龍"}, Readings: []string{"あさしょうりゅう"}, Meanings: []Meaning{{Gloss: "Asashōryū"}}},
+	})
+	assert.Equal(t, 2, g.Len())
+
+	t.Run("Lookup", func(t *testing.T) {
+		res, err := r.Lookup(context.Background(), "上手")
+		assert.Nil(t, err)
+		assert.Equal(t, 2, len(res))
+		assert.False(t, res[0].User)
+		assert.True(t, res[1].User)
+	})
+
+	t.Run("LookupMany", func(t *testing.T) {
+		res, err := r.LookupMany(context.Background(), []string{"朝青龍", "へた"})
+		assert.Nil(t, err)
+		assert.Equal(t, 1, len(res["朝青龍"]))
+		assert.Empty(t, res["へた"])
+	})
+
+	t.Run("Search", func(t *testing.T) {
+		s := SearchWithGlossary(repo.(Searcher), g)
+		sequences := func(res SearchResult) []int {
+			result := make([]int, 0)
+			for _, e := range res.Entries {
+				result = append(result, e.Sequence)
+			}
+			return result
+		}
+		for _, test := range []struct {
+			offset, limit int
+			expected      []int
+		}{
+			{0, 10, []int{0, 1}},
+			{0, 1, []int{0}},
+			{1, 1, []int{1}},
+			{2, 1, []int{}},
+		} {
+			res, err := s.Search(context.Background(), SearchQuery{Text: "上手", Mode: SearchExact, Offset: test.offset, Limit: test.limit})
+			assert.Nil(t, err)
+			assert.Equal(t, 2, res.Total)
+			assert.Equal(t, test.expected, sequences(res))
+		}
+
+		e, err := s.Entry(context.Background(), 9000001)
+		assert.Nil(t, err)
+		assert.True(t, e.User)
+		e, err = s.Entry(context.Background(), 1)
+		assert.Nil(t, err)
+		assert.Equal(t, "skill", e.Meanings[0].Gloss)
+	})
+
+	t.Run("unfiltered and ranked first", func(t *testing.T) {
+		res, _ := r.Lookup(context.Background(), "上手")
+		token := Token{Surface: "上手", Base: "上手", POS: []string{"名詞", "一般", "*", "*"}}
+		token = token.SetEntries(res).ApplyStrictness(StrictnessStrict)
+		assert.True(t, token.Entries[0].User)
+		assert.True(t, token.Entries[0].Meanings[0].Match.Matched)
+
+		// Kept even though the token is read じょうず
+		token = Token{Surface: "上手", Base: "上手", Reading: "ジョウズ", POS: []string{"名詞", "一般", "*", "*"}}
+		token = token.SetEntries(res).ApplyStrictness(StrictnessStrict)
+		assert.Equal(t, 2, len(token.Entries))
+	})
+}
@@ -0,0 +1,37 @@
+package dictionary
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/go-kit/kit/metrics"
+)
+
+// instrumented - times the lookups of the Repository it wraps
+type instrumented struct {
+	Repository
+	latency metrics.Histogram
+}
+
+// NewInstrumented - Repository timing Lookup and LookupMany in latency,
+// labelled by method and whether the lookup failed, cache and db together
+// Only those two are wrapped; type assert on r, not the result, for
+// Searcher, Invalidator and the like
+func NewInstrumented(r Repository, latency metrics.Histogram) Repository {
+	return &instrumented{Repository: r, latency: latency}
+}
+
+func (r *instrumented) Lookup(ctx context.Context, query string) (entries []Entry, err error) {
+	defer r.observe("lookup", time.Now(), &err)
+	return r.Repository.Lookup(ctx, query)
+}
+
+func (r *instrumented) LookupMany(ctx context.Context, queries []string) (result map[string][]Entry, err error) {
+	defer r.observe("lookup_many", time.Now(), &err)
+	return r.Repository.LookupMany(ctx, queries)
+}
+
+func (r *instrumented) observe(method string, begin time.Time, err *error) {
+	r.latency.With("method", method, "error", fmt.Sprint(*err != nil)).Observe(time.Since(begin).Seconds())
+}
@@ -0,0 +1,28 @@
+package dictionary
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/gilmoreg/seibiki/internal/metrics/metricstest"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestInstrumented(t *testing.T) {
+	latency := metricstest.NewHistogram()
+	r := NewInstrumented(NewMemory([]Entry{{Sequence: 1, Kanji: []string{"飲む"}}}), latency)
+
+	_, err := r.Lookup(context.Background(), "飲む")
+	assert.Nil(t, err)
+	_, err = r.LookupMany(context.Background(), []string{"飲む", "寒い"})
+	assert.Nil(t, err)
+	assert.Equal(t, 1, latency.Count("method", "lookup", "error", "false"))
+	assert.Equal(t, 1, latency.Count("method", "lookup_many", "error", "false"))
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	_, err = r.Lookup(ctx, "飲む")
+	assert.True(t, errors.Is(err, context.Canceled))
+	assert.Equal(t, 1, latency.Count("method", "lookup", "error", "true"))
+}
@@ -1,19 +1,203 @@
 package dictionary
 
 import (
-	"github.com/ikawaha/kagome.ipadic/tokenizer"
+	"errors"
+	"fmt"
+	"os"
+	"sync"
+
+	"github.com/ikawaha/kagome/tokenizer"
+)
+
+// SysDic - Kagome system dictionary text is tokenized with
+// Each has its own part of speech scheme and token features
+type SysDic string
+
+const (
+	// SysDicIPA - IPADIC, the default
+	SysDicIPA SysDic = "ipadic"
+	// SysDicUni - UniDic, with finer parts of speech and lemmas
+	// that group variant spellings; needs about 600MB more memory
+	SysDicUni SysDic = "unidic"
+)
+
+// Valid - true if d is a supported SysDic
+func (d SysDic) Valid() bool {
+	switch d {
+	case SysDicIPA, SysDicUni:
+		return true
+	}
+	return false
+}
+
+func (d SysDic) dic() tokenizer.Dic {
+	if d == SysDicUni {
+		return tokenizer.SysDicUni()
+	}
+	return tokenizer.SysDicIPA()
+}
+
+// Mode - how Kagome segments text
+type Mode string
+
+const (
+	// ModeNormal - the most likely segmentation
+	ModeNormal Mode = "normal"
+	// ModeSearch - also splits long compounds, e.g. 関西国際空港
+	// into 関西 国際 空港, so their parts are found in the dictionary
+	ModeSearch Mode = "search"
+	// ModeExtended - search, and also splits unknown words into characters
+	ModeExtended Mode = "extended"
 )
 
-var t = tokenizer.New()
+// Valid - true if m is a supported Mode
+func (m Mode) Valid() bool {
+	switch m {
+	case ModeNormal, ModeSearch, ModeExtended:
+		return true
+	}
+	return false
+}
+
+func (m Mode) kagome() tokenizer.TokenizeMode {
+	switch m {
+	case ModeNormal:
+		return tokenizer.Normal
+	case ModeExtended:
+		return tokenizer.Extended
+	}
+	return tokenizer.Search
+}
+
+// TokenizeOptions - system dictionary and mode for a single tokenization
+// Empty fields take the Tokenizer's defaults
+type TokenizeOptions struct {
+	SysDic SysDic
+	Mode   Mode
+}
+
+// Tokenizer - Kagome loaded with one or more system dictionaries,
+// and optionally a user dictionary that can be reloaded while in use
+type Tokenizer struct {
+	mu         sync.RWMutex
+	tokenizers map[SysDic]tokenizer.Tokenizer
+	defaults   TokenizeOptions
+}
+
+// NewTokenizer - Tokenizer loading every dictionary in sysdics, of
+// which the first and mode are used unless TokenizeOptions say otherwise
+// Loading UniDic takes a few seconds
+func NewTokenizer(sysdics []SysDic, mode Mode) (*Tokenizer, error) {
+	if len(sysdics) == 0 {
+		return nil, errors.New("no tokenizer dictionary")
+	}
+	if !mode.Valid() {
+		return nil, fmt.Errorf("unknown tokenizer mode %q", mode)
+	}
+	tok := &Tokenizer{
+		tokenizers: make(map[SysDic]tokenizer.Tokenizer, len(sysdics)),
+		defaults:   TokenizeOptions{SysDic: sysdics[0], Mode: mode},
+	}
+	for _, d := range sysdics {
+		if !d.Valid() {
+			return nil, fmt.Errorf("unknown tokenizer dictionary %q", d)
+		}
+		tok.tokenizers[d] = tokenizer.NewWithDic(d.dic())
+	}
+	return tok, nil
+}
+
+// Tokenize - use Kagome to tokenize input string, collect into words
+// Errors if o asks for a dictionary that was not loaded
+func (tok *Tokenizer) Tokenize(query string, o TokenizeOptions) ([]Word, error) {
+	o = tok.Options(o)
+	tok.mu.RLock()
+	kt, ok := tok.tokenizers[o.SysDic]
+	tok.mu.RUnlock()
+	if !ok {
+		return nil, fmt.Errorf("tokenizer dictionary %s is not loaded", o.SysDic)
+	}
+	return segment(kt.Analyze(query, o.Mode.kagome()), o.SysDic), nil
+}
+
+// LoadUserDic - replace the user dictionary of every system dictionary
+// with the one at path, returning the number of words in it; an empty
+// path removes it. If the file cannot be read the old one is kept
+// Each line is "surface,segmentation,readings,part of speech", e.g.
+//
+//	朝青龍,朝青龍,アサショウリュウ,カスタム人名
+//	関西国際空港,関西 国際 空港,カンサイ コクサイ クウコウ,カスタム名詞
+//
+// Its words become single USER tokens that take precedence over
+// Kagome's own segmentation
+func (tok *Tokenizer) LoadUserDic(path string) (int, error) {
+	var udic tokenizer.UserDic
+	n := 0
+	if path != "" {
+		f, err := os.Open(path)
+		if err != nil {
+			return 0, err
+		}
+		defer f.Close()
+		records, err := tokenizer.NewUserDicRecords(f)
+		if err != nil {
+			return 0, fmt.Errorf("%s: %s", path, err)
+		}
+		if udic, err = records.NewUserDic(); err != nil {
+			return 0, fmt.Errorf("%s: %s", path, err)
+		}
+		n = len(records)
+	}
+	tok.mu.Lock()
+	defer tok.mu.Unlock()
+	tokenizers := make(map[SysDic]tokenizer.Tokenizer, len(tok.tokenizers))
+	for d, kt := range tok.tokenizers {
+		kt.SetUserDic(udic)
+		tokenizers[d] = kt
+	}
+	tok.tokenizers = tokenizers
+	return n, nil
+}
 
-func segment(tokens []tokenizer.Token) []Word {
+// Options - o with empty fields set to the defaults
+func (tok *Tokenizer) Options(o TokenizeOptions) TokenizeOptions {
+	if o.SysDic == "" {
+		o.SysDic = tok.defaults.SysDic
+	}
+	if o.Mode == "" {
+		o.Mode = tok.defaults.Mode
+	}
+	return o
+}
+
+// Check - error unless every dictionary is loaded and segmenting text
+func (tok *Tokenizer) Check() error {
+	tok.mu.RLock()
+	sysdics := make([]SysDic, 0, len(tok.tokenizers))
+	for d := range tok.tokenizers {
+		sysdics = append(sysdics, d)
+	}
+	tok.mu.RUnlock()
+	for _, d := range sysdics {
+		words, err := tok.Tokenize("寒い", TokenizeOptions{SysDic: d, Mode: ModeNormal})
+		if err != nil {
+			return err
+		}
+		if len(words) != 1 || len(words[0].Tokens) != 1 || words[0].Tokens[0].POS[0] == "*" {
+			return fmt.Errorf("%s tokenizer is not ready", d)
+		}
+	}
+	return nil
+}
+
+func segment(tokens []tokenizer.Token, d SysDic) []Word {
 	words := make([]Word, 0)
 	currentWord := make([]Token, 0)
 	for _, t := range tokens {
-		if t.Class == tokenizer.DUMMY { // BOS and EOS
+		if t.ID < 0 { // BOS and EOS
 			continue
 		}
-		token := Convert(t)
+		token := Convert(t, d)
 
 		// If we are at a punctuation mark or
 		// a word whose base is equal to its surface
@@ -46,10 +230,3 @@ func segment(tokens []tokenizer.Token) []Word {
 	}
 	return words
 }
-
-// Tokenize - use Kagome to tokenize input string, collect into words
-func Tokenize(query string) []Word {
-	tokens := t.Analyze(query, tokenizer.Search)
-	words := segment(tokens)
-	return words
-}
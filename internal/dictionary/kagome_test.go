@@ -1,11 +1,23 @@
 package dictionary
 
 import (
+	"os"
+	"path/filepath"
 	"testing"
 
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 )
 
+// tokenize - words of query, split the way lookups are by default
+func tokenize(t *testing.T, query string) []Word {
+	tok, err := NewTokenizer([]SysDic{SysDicIPA}, ModeSearch)
+	require.NoError(t, err)
+	words, err := tok.Tokenize(query, TokenizeOptions{})
+	require.NoError(t, err)
+	return words
+}
+
 func TestTokenizer(t *testing.T) {
 	tests := []struct {
 		sentence string
@@ -19,8 +31,72 @@ func TestTokenizer(t *testing.T) {
 
 	for _, test := range tests {
 		t.Run(test.sentence, func(t *testing.T) {
-			res := Tokenize(test.sentence)
+			res := tokenize(t, test.sentence)
 			assert.Equal(t, test.count, len(res))
 		})
 	}
 }
+
+func TestTokenizerModes(t *testing.T) {
+	tok, err := NewTokenizer([]SysDic{SysDicIPA}, ModeSearch)
+	assert.Nil(t, err)
+	assert.Nil(t, tok.Check())
+
+	words, err := tok.Tokenize("関西国際空港", TokenizeOptions{})
+	assert.Nil(t, err)
+	assert.Equal(t, 3, len(words))
+	assert.Empty(t, words[0].Tokens[0].SysDic, "IPADIC is the default and left out")
+	words, err = tok.Tokenize("関西国際空港", TokenizeOptions{Mode: ModeNormal})
+	assert.Nil(t, err)
+	assert.Equal(t, 1, len(words))
+
+	// Unknown words are split into characters, which have no base form
+	words, err = tok.Tokenize("ﾃｽﾄ", TokenizeOptions{Mode: ModeExtended})
+	assert.Nil(t, err)
+	assert.Equal(t, 3, len(words[0].Tokens))
+	assert.Empty(t, words[0].Bases())
+
+	_, err = tok.Tokenize("寒い", TokenizeOptions{SysDic: SysDicUni})
+	assert.NotNil(t, err)
+}
+
+func TestNewTokenizer(t *testing.T) {
+	_, err := NewTokenizer(nil, ModeSearch)
+	assert.NotNil(t, err)
+	_, err = NewTokenizer([]SysDic{"jumandic"}, ModeSearch)
+	assert.NotNil(t, err)
+	_, err = NewTokenizer([]SysDic{SysDicIPA}, "fast")
+	assert.NotNil(t, err)
+}
+
+func TestLoadUserDic(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "userdic.csv")
+	err := os.WriteFile(path, []byte("# names\n朝青龍,朝青龍,アサショウリュウ,カスタム人名\n"), 0644)
+	assert.Nil(t, err)
+	tok, err := NewTokenizer([]SysDic{SysDicIPA}, ModeSearch)
+	assert.Nil(t, err)
+
+	n, err := tok.LoadUserDic(path)
+	assert.Nil(t, err)
+	assert.Equal(t, 1, n)
+	words, err := tok.Tokenize("朝青龍", TokenizeOptions{})
+	assert.Nil(t, err)
+	assert.Equal(t, 1, len(words))
+	token := words[0].Tokens[0]
+	assert.Equal(t, "USER", token.Class)
+	assert.Equal(t, "朝青龍", token.Base)
+	assert.Equal(t, "アサショウリュウ", token.Reading)
+	assert.Equal(t, "カスタム人名", token.POS[0])
+
+	// A bad file leaves the old dictionary in place
+	_, err = tok.LoadUserDic(filepath.Join(t.TempDir(), "missing.csv"))
+	assert.NotNil(t, err)
+	words, _ = tok.Tokenize("朝青龍", TokenizeOptions{})
+	assert.Equal(t, "USER", words[0].Tokens[0].Class)
+
+	n, err = tok.LoadUserDic("")
+	assert.Nil(t, err)
+	assert.Equal(t, 0, n)
+	words, _ = tok.Tokenize("朝青龍", TokenizeOptions{})
+	assert.NotEqual(t, "USER", words[0].Tokens[0].Class)
+}
@@ -0,0 +1,80 @@
+package dictionary
+
+import (
+	"context"
+	"sort"
+)
+
+// memory - in-process Repository holding the whole dictionary,
+// indexed by kanji, by reading and by sequence number
+type memory struct {
+	entries   []Entry
+	kanji     map[string][]int
+	readings  map[string][]int
+	sequences map[int]int
+}
+
+// NewMemory - new Repository backed by entries held in memory
+// Needs neither MongoDB nor Redis
+func NewMemory(entries []Entry) Repository {
+	m := &memory{
+		entries:   entries,
+		kanji:     make(map[string][]int),
+		readings:  make(map[string][]int),
+		sequences: make(map[int]int, len(entries)),
+	}
+	for i, entry := range entries {
+		m.sequences[entry.Sequence] = i
+		for _, k := range entry.Kanji {
+			m.kanji[k] = append(m.kanji[k], i)
+		}
+		for _, r := range entry.Readings {
+			m.readings[r] = append(m.readings[r], i)
+		}
+	}
+	return m
+}
+
+// Lookup - find entries whose readings or kanji equal query
+func (m *memory) Lookup(ctx context.Context, query string) ([]Entry, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	return m.find(query), nil
+}
+
+// LookupMany - find entries for several queries at once
+func (m *memory) LookupMany(ctx context.Context, queries []string) (map[string][]Entry, error) {
+	queries = dedupe(queries)
+	result := make(map[string][]Entry, len(queries))
+	for _, query := range queries {
+		if err := ctx.Err(); err != nil {
+			return result, err
+		}
+		result[query] = m.find(query)
+	}
+	return result, nil
+}
+
+// find - entries matching query, in the order they were loaded
+func (m *memory) find(query string) []Entry {
+	matched := make(map[int]bool)
+	indexes := make([]int, 0)
+	for _, index := range []map[string][]int{m.readings, m.kanji} {
+		for _, i := range index[query] {
+			if !matched[i] {
+				matched[i] = true
+				indexes = append(indexes, i)
+			}
+		}
+	}
+	if len(indexes) == 0 {
+		return nil
+	}
+	sort.Ints(indexes)
+	result := make([]Entry, len(indexes))
+	for n, i := range indexes {
+		result[n] = m.entries[i]
+	}
+	return result
+}
@@ -0,0 +1,37 @@
+package dictionary
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMemory(t *testing.T) {
+	m := NewMemory([]Entry{
+		{Sequence: 1, Kanji: []string{"上手"}, Readings: []string{"じょうず"}},
+		{Sequence: 2, Kanji: []string{"上手"}, Readings: []string{"うわて"}},
+		{Sequence: 3, Readings: []string{"じょうず"}},
+	})
+
+	t.Run("Lookup by kanji", func(t *testing.T) {
+		res, err := m.Lookup(context.Background(), "上手")
+		assert.Nil(t, err)
+		assert.Equal(t, 2, len(res))
+		assert.Equal(t, 1, res[0].Sequence)
+	})
+
+	t.Run("LookupMany by reading", func(t *testing.T) {
+		res, err := m.LookupMany(context.Background(), []string{"じょうず", "へた"})
+		assert.Nil(t, err)
+		assert.Equal(t, 2, len(res["じょうず"]))
+		assert.Empty(t, res["へた"])
+	})
+
+	t.Run("cancelled context", func(t *testing.T) {
+		ctx, cancel := context.WithCancel(context.Background())
+		cancel()
+		_, err := m.Lookup(ctx, "上手")
+		assert.Equal(t, context.Canceled, err)
+	})
+}
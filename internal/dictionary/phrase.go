@@ -0,0 +1,153 @@
+package dictionary
+
+import (
+	"github.com/gilmoreg/seibiki/internal/transliterate"
+)
+
+// Kagome splits set expressions such as 気にする or 仕方がない into
+// several words. After the per-token lookup, runs of adjacent words are
+// looked up as a whole and the longest match starting at each word is
+// joined into a single Word carrying the phrase's entries.
+
+// maxPhraseWords - longest run of words tried as one phrase
+const maxPhraseWords = 5
+
+// phraseable - true if words could form a phrase
+// Phrases never cross punctuation and neither start nor end on a
+// particle or auxiliary, so これ+は is not joined into これは
+func phraseable(words []Word) bool {
+	if len(words) < 2 {
+		return false
+	}
+	for _, word := range words {
+		if word.IsPunctuation() {
+			return false
+		}
+	}
+	return !isFunctional(words[0].Tokens[0]) && !isFunctional(words[len(words)-1].Tokens[0])
+}
+
+// isFunctional - true if token is a particle or auxiliary verb
+func isFunctional(t Token) bool {
+	return t.POS[0] == "助詞" || t.POS[0] == "助動詞"
+}
+
+// phraseKey - dictionary form of words read as one phrase:
+// every word as written up to the head of the last one, which is
+// replaced by its base form, so 気にしない is looked up as 気にする
+func phraseKey(words []Word) string {
+	key := ""
+	for _, word := range words[:len(words)-1] {
+		key += word.Surface
+	}
+	head := words[len(words)-1].Tokens[0]
+	if head.Base == "*" {
+		return key + head.Surface
+	}
+	return key + head.Base
+}
+
+// phraseEnd - index one past the last word a phrase starting at i may cover
+func phraseEnd(words []Word, i int) int {
+	if end := i + maxPhraseWords; end < len(words) {
+		return end
+	}
+	return len(words)
+}
+
+// PhraseCandidates - dictionary forms of every run of adjacent words
+// that could be a phrase
+func PhraseCandidates(words []Word) []string {
+	candidates := make([]string, 0)
+	for i := range words {
+		for j := i + 2; j <= phraseEnd(words, i); j++ {
+			if phraseable(words[i:j]) {
+				candidates = append(candidates, phraseKey(words[i:j]))
+			}
+		}
+	}
+	return candidates
+}
+
+// JoinPhrases - join runs of words with phrase entries into single Words
+// Working left to right, the longest phrase starting at each word wins;
+// the joined Word keeps every token with its own entries
+func JoinPhrases(words []Word, entries map[string][]Entry) []Word {
+	result := make([]Word, 0)
+	for i := 0; i < len(words); {
+		joined := false
+		for j := phraseEnd(words, i); j >= i+2; j-- {
+			span := words[i:j]
+			if !phraseable(span) {
+				continue
+			}
+			key := phraseKey(span)
+			if len(entries[key]) == 0 {
+				continue
+			}
+			result = append(result, newPhrase(span, key, entries[key]))
+			i = j
+			joined = true
+			break
+		}
+		if !joined {
+			result = append(result, words[i])
+			i++
+		}
+	}
+	return result
+}
+
+// newPhrase - single Word made of words, with the phrase's entries
+func newPhrase(words []Word, base string, entries []Entry) Word {
+	tokens := make([]Token, 0)
+	for _, word := range words {
+		tokens = append(tokens, word.Tokens...)
+	}
+	result := NewWord(tokens)
+	result.Base = base
+	result.Entries = entries
+	return result
+}
+
+// rankPhrase - the phrase's entries, most likely first
+// A phrase has no single part of speech, so no meanings are dropped;
+// StrictnessStrict only keeps the entries read the way the phrase is
+func (w Word) rankPhrase(s Strictness) []Entry {
+	t := w.phraseToken()
+	entries := make([]Entry, 0, len(w.Entries))
+	for _, entry := range w.Entries {
+		entry.Meanings = append([]Meaning(nil), entry.Meanings...)
+		t.rank(entry.Meanings)
+		entry.Score = t.entryScore(entry)
+		entries = append(entries, entry)
+	}
+	if s == StrictnessStrict {
+		entries = t.restrictToReading(entries)
+	}
+	rankEntries(entries)
+	return entries
+}
+
+// phraseToken - Token for the phrase's dictionary form, read as its
+// tokens are up to the one conjugated: 気にしない (キ ニ シ ナイ) → きにする
+// Has no reading if a token has none
+func (w Word) phraseToken() Token {
+	t := Token{Surface: w.Base, Base: w.Base}
+	surface, reading := "", ""
+	for _, token := range w.Tokens {
+		if surface+token.Base == w.Base {
+			if base := token.baseReading(); base != "" {
+				t.Reading = reading + base
+			}
+			break
+		}
+		r := transliterate.ToHiragana(token.Reading)
+		if r == "" || r == "*" {
+			break
+		}
+		surface += token.Surface
+		reading += r
+	}
+	return t
+}
@@ -0,0 +1,57 @@
+package dictionary
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPhraseCandidates(t *testing.T) {
+	candidates := PhraseCandidates(tokenize(t, "気にしないで"))
+	assert.Contains(t, candidates, "気にする")
+	// never ends on a particle
+	assert.NotContains(t, candidates, "気に")
+
+	assert.Contains(t, PhraseCandidates(tokenize(t, "仕方がない")), "仕方がない")
+	assert.Empty(t, PhraseCandidates(tokenize(t, "これは")))
+	assert.Empty(t, PhraseCandidates(tokenize(t, "寒い。寒い")))
+}
+
+func TestJoinPhrases(t *testing.T) {
+	r := NewMemory([]Entry{
+		{Sequence: 1, Kanji: []string{"気にする"}, Readings: []string{"きにする"}},
+		{Sequence: 2, Kanji: []string{"一生懸命"}, Readings: []string{"いっしょうけんめい"}},
+		{Sequence: 3, Kanji: []string{"一生懸命に働く"}, Readings: []string{"いっしょうけんめいにはたらく"}},
+		{Sequence: 4, Kanji: []string{"一生"}, Readings: []string{"いっしょう"}},
+	})
+	lookup := func(query string) []Word {
+		words := tokenize(t, query)
+		entries, err := r.LookupMany(context.Background(), PhraseCandidates(words))
+		assert.Nil(t, err)
+		return JoinPhrases(words, entries)
+	}
+
+	t.Run("conjugated phrase", func(t *testing.T) {
+		words := lookup("気にしないで")
+		assert.Equal(t, 2, len(words))
+		assert.Equal(t, "気にしない", words[0].Surface)
+		assert.Equal(t, "気にする", words[0].Base)
+		assert.Equal(t, 1, words[0].Entries[0].Sequence)
+		// tokens are kept underneath
+		assert.Equal(t, 4, len(words[0].Tokens))
+		assert.Equal(t, "で", words[1].Surface)
+	})
+
+	t.Run("longest match wins", func(t *testing.T) {
+		words := lookup("一生懸命に働いた")
+		assert.Equal(t, 1, len(words))
+		assert.Equal(t, 3, words[0].Entries[0].Sequence)
+	})
+
+	t.Run("no phrase", func(t *testing.T) {
+		words := lookup("一生の")
+		assert.Equal(t, 2, len(words))
+		assert.Empty(t, words[0].Entries)
+	})
+}
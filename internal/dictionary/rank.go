@@ -0,0 +1,95 @@
+package dictionary
+
+import (
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Scores added up to rank the entries and meanings found for a token
+const (
+	// scoreUser - entry is from the user glossary, which is there
+	// to say what a word means in the texts being read
+	scoreUser = 5.0
+	// scorePOS - meaning fits the token's part of speech
+	scorePOS = 4.0
+	// scoreReading - the token's reading is one of the entry's
+	scoreReading = 3.0
+	// scoreCommon - entry has a priority tag marking it as common
+	// (news1, ichi1, spec1, spec2, gai1); half for the second tier
+	scoreCommon = 2.0
+	// scoreKana - token written in kana, meaning usually written in kana (&uk;)
+	scoreKana = 1.0
+	// scoreFrequency - most for an nf01 entry, least for nf48
+	scoreFrequency = 0.5
+)
+
+var commonTags = map[string]float64{
+	"news1": scoreCommon, "ichi1": scoreCommon, "spec1": scoreCommon,
+	"spec2": scoreCommon, "gai1": scoreCommon,
+	"news2": scoreCommon / 2, "ichi2": scoreCommon / 2, "gai2": scoreCommon / 2,
+}
+
+// rank - sort meanings best first, by how well each fits the token
+func (t Token) rank(meanings []Meaning) {
+	sort.SliceStable(meanings, func(i, j int) bool {
+		return t.meaningScore(meanings[i]) > t.meaningScore(meanings[j])
+	})
+}
+
+// rankEntries - sort entries by Score, best first
+func rankEntries(entries []Entry) {
+	sort.SliceStable(entries, func(i, j int) bool {
+		return entries[i].Score > entries[j].Score
+	})
+}
+
+func (t Token) meaningScore(m Meaning) float64 {
+	score := 0.0
+	if m.Match != nil && m.Match.Matched {
+		score += scorePOS
+	}
+	if kanaOnly(t.Surface) && in("&uk;", m.Misc) {
+		score += scoreKana
+	}
+	return score
+}
+
+// entryScore - score of the entry's best meaning, plus the entry's
+// reading and priority
+func (t Token) entryScore(e Entry) float64 {
+	score := 0.0
+	for _, m := range e.Meanings {
+		if s := t.meaningScore(m); s > score {
+			score = s
+		}
+	}
+	if readsAs(e, t.baseReading()) {
+		score += scoreReading
+	}
+	common := 0.0
+	for _, tag := range e.Priority {
+		if s := commonTags[tag]; s > common {
+			common = s
+		}
+		if strings.HasPrefix(tag, "nf") {
+			if n, err := strconv.Atoi(tag[2:]); err == nil && n >= 1 && n <= 48 {
+				score += scoreFrequency * float64(49-n) / 48
+			}
+		}
+	}
+	if e.User {
+		score += scoreUser
+	}
+	return score + common
+}
+
+// kanaOnly - true if s is written entirely in kana
+func kanaOnly(s string) bool {
+	for _, r := range s {
+		if !isKana(r) {
+			return false
+		}
+	}
+	return s != ""
+}
@@ -0,0 +1,65 @@
+package dictionary
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRank(t *testing.T) {
+	adj := []string{"形容詞", "自立", "*", "*"}
+	noun := []string{"名詞", "一般", "*", "*"}
+	sequences := func(t Token) []int {
+		res := make([]int, 0)
+		for _, e := range t.Entries {
+			res = append(res, e.Sequence)
+		}
+		return res
+	}
+
+	t.Run("priority", func(t *testing.T) {
+		token := Token{Surface: "寒い", POS: adj}.SetEntries([]Entry{
+			{Sequence: 1, Meanings: meanings("&adj-i;")},
+			{Sequence: 2, Meanings: meanings("&adj-i;"), Priority: []string{"ichi1"}},
+			{Sequence: 3, Meanings: meanings("&adj-i;"), Priority: []string{"ichi2", "nf40"}},
+		}).ApplyStrictness(StrictnessStrict)
+		assert.Equal(t, []int{2, 3, 1}, sequences(token))
+		assert.Equal(t, scorePOS+scoreCommon, token.Entries[0].Score)
+	})
+
+	t.Run("reading", func(t *testing.T) {
+		token := Token{Surface: "上手", Base: "上手", Reading: "ジョウズ", POS: noun}.SetEntries([]Entry{
+			{Sequence: 1, Readings: []string{"うわて"}, Meanings: meanings("&n;"), Priority: []string{"ichi1"}},
+			{Sequence: 2, Readings: []string{"じょうず"}, Meanings: meanings("&n;")},
+		}).ApplyStrictness(StrictnessRanked)
+		assert.Equal(t, []int{2, 1}, sequences(token))
+	})
+
+	t.Run("usually kana", func(t *testing.T) {
+		entries := []Entry{{Sequence: 1, Meanings: []Meaning{
+			{Gloss: "delicious", PartOfSpeech: []string{"&adj-i;"}},
+			{Gloss: "skillful", PartOfSpeech: []string{"&adj-i;"}, Misc: []string{"&uk;"}},
+		}}}
+		kana := Token{Surface: "うまい", POS: adj}.SetEntries(entries).ApplyStrictness(StrictnessStrict)
+		assert.Equal(t, "skillful", kana.Entries[0].Meanings[0].Gloss)
+		kanji := Token{Surface: "旨い", POS: adj}.SetEntries(entries).ApplyStrictness(StrictnessStrict)
+		assert.Equal(t, "delicious", kanji.Entries[0].Meanings[0].Gloss)
+	})
+
+	t.Run("ranked keeps unmatched last", func(t *testing.T) {
+		token := Token{Surface: "寒い", POS: adj}.SetEntries([]Entry{
+			{Sequence: 1, Meanings: meanings("&n;"), Priority: []string{"ichi1", "news1"}},
+			{Sequence: 2, Meanings: meanings("&adj-i;")},
+		}).ApplyStrictness(StrictnessRanked)
+		assert.Equal(t, []int{2, 1}, sequences(token))
+	})
+
+	t.Run("off keeps dictionary order", func(t *testing.T) {
+		token := Token{Surface: "寒い", POS: adj}.SetEntries([]Entry{
+			{Sequence: 1, Meanings: meanings("&adj-i;")},
+			{Sequence: 2, Meanings: meanings("&adj-i;"), Priority: []string{"ichi1"}},
+		}).ApplyStrictness(StrictnessOff)
+		assert.Equal(t, []int{1, 2}, sequences(token))
+		assert.Zero(t, token.Entries[0].Score)
+	})
+}
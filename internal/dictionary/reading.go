@@ -0,0 +1,73 @@
+package dictionary
+
+import (
+	"strings"
+
+	"github.com/gilmoreg/seibiki/internal/transliterate"
+)
+
+// baseReading - hiragana reading of the token's base form, worked out from
+// the reading Kagome gave its surface: 飲ん (ノン) → のむ
+// Empty if there is no reading or it does not end in the surface's kana
+func (t Token) baseReading() string {
+	reading := transliterate.ToHiragana(t.Reading)
+	if reading == "" || reading == "*" || t.Base == "" || t.Base == "*" {
+		return ""
+	}
+	surface := transliterate.ToHiragana(t.Surface)
+	base := transliterate.ToHiragana(t.Base)
+	if surface == base {
+		return reading
+	}
+	// Swap the surface's kana ending for the base form's
+	ending := kanaSuffix(surface)
+	if !strings.HasSuffix(reading, ending) {
+		return ""
+	}
+	return strings.TrimSuffix(reading, ending) + kanaSuffix(base)
+}
+
+// kanaSuffix - the run of kana s ends with
+func kanaSuffix(s string) string {
+	runes := []rune(s)
+	i := len(runes)
+	for i > 0 && isKana(runes[i-1]) {
+		i--
+	}
+	return string(runes[i:])
+}
+
+// readsAs - true if reading is one of the entry's readings
+func readsAs(e Entry, reading string) bool {
+	if reading == "" {
+		return false
+	}
+	for _, r := range e.Readings {
+		if transliterate.ToHiragana(r) == reading {
+			return true
+		}
+	}
+	return false
+}
+
+// restrictToReading - the entries read the way the token is,
+// or all of them if none are
+// Separates homographs such as 生 (なま, せい, き) or 上手 (じょうず, うわて)
+// User glossary entries are always kept
+func (t Token) restrictToReading(entries []Entry) []Entry {
+	reading := t.baseReading()
+	restricted := make([]Entry, 0)
+	found := false
+	for _, entry := range entries {
+		if readsAs(entry, reading) {
+			found = true
+			restricted = append(restricted, entry)
+		} else if entry.User {
+			restricted = append(restricted, entry)
+		}
+	}
+	if !found {
+		return entries
+	}
+	return restricted
+}
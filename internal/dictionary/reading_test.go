@@ -0,0 +1,54 @@
+package dictionary
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestBaseReading(t *testing.T) {
+	tests := []struct {
+		surface, base, reading, expected string
+	}{
+		{"上手", "上手", "ジョウズ", "じょうず"},
+		{"飲ん", "飲む", "ノン", "のむ"},
+		{"生き", "生きる", "イキ", "いきる"},
+		{"のん", "のむ", "ノン", "のむ"},
+		{"ココア", "ココア", "ココア", "ここあ"},
+		// unknown words have no reading
+		{"ググ", "*", "", ""},
+		// reading does not end in the surface's kana
+		{"飲ん", "飲む", "ノミ", ""},
+	}
+	for _, test := range tests {
+		t.Run(test.surface, func(t *testing.T) {
+			token := Token{Surface: test.surface, Base: test.base, Reading: test.reading}
+			assert.Equal(t, test.expected, token.baseReading())
+		})
+	}
+}
+
+func TestRestrictToReading(t *testing.T) {
+	noun := []string{"名詞", "一般", "*", "*"}
+	entries := []Entry{
+		{Sequence: 1, Kanji: []string{"生"}, Readings: []string{"せい"}, Meanings: meanings("&n;")},
+		{Sequence: 2, Kanji: []string{"生"}, Readings: []string{"なま"}, Meanings: meanings("&n;")},
+		{Sequence: 3, Kanji: []string{"生"}, Readings: []string{"き"}, Meanings: meanings("&n;")},
+	}
+	sequences := func(t Token) []int {
+		res := make([]int, 0)
+		for _, e := range t.Entries {
+			res = append(res, e.Sequence)
+		}
+		return res
+	}
+
+	nama := Token{Surface: "生", Base: "生", Reading: "ナマ", POS: noun}.SetEntries(entries)
+	assert.Equal(t, []int{2}, sequences(nama.ApplyStrictness(StrictnessStrict)))
+	// ranked only prefers the reading
+	assert.Equal(t, []int{2, 1, 3}, sequences(nama.ApplyStrictness(StrictnessRanked)))
+
+	// no entry has the reading, so all are kept
+	shou := Token{Surface: "生", Base: "生", Reading: "ショウ", POS: noun}.SetEntries(entries)
+	assert.Equal(t, []int{1, 2, 3}, sequences(shou.ApplyStrictness(StrictnessStrict)))
+}
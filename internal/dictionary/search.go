@@ -0,0 +1,242 @@
+package dictionary
+
+import (
+	"context"
+	"errors"
+	"regexp"
+	"strings"
+	"unicode"
+
+	"github.com/mongodb/mongo-go-driver/bson"
+)
+
+// Searcher - Repository that can search entries directly, without tokenizing,
+// and fetch them by JMdict sequence number
+type Searcher interface {
+	Search(ctx context.Context, q SearchQuery) (SearchResult, error)
+	Entry(ctx context.Context, sequence int) (Entry, error)
+}
+
+// ErrNotFound - no entry has the sequence number asked for
+var ErrNotFound = errors.New("entry not found")
+
+// SearchMode - how SearchQuery.Text is matched
+type SearchMode string
+
+const (
+	// SearchExact - kanji or reading equal to the text
+	SearchExact SearchMode = "exact"
+	// SearchPrefix - kanji or reading starting with the text
+	SearchPrefix SearchMode = "prefix"
+	// SearchWildcard - kanji or reading matching the text, where * stands
+	// for any run of characters and ? for any one
+	// The text must start with a character that is neither, so the
+	// kanji and reading indexes can narrow the search
+	SearchWildcard SearchMode = "wildcard"
+	// SearchGloss - English meaning containing the text as whole words,
+	// case insensitively; MongoDB searches its text index on glosses
+	SearchGloss SearchMode = "gloss"
+)
+
+// MaxSearchTotal - most matching entries SearchResult.Total counts,
+// so a broad search does not count the whole collection for every page
+const MaxSearchTotal = 1000
+
+// wildcards - characters standing for others in SearchWildcard text
+const wildcards = "*?＊？"
+
+// Valid - true if m is a supported SearchMode
+func (m SearchMode) Valid() bool {
+	switch m {
+	case SearchExact, SearchPrefix, SearchWildcard, SearchGloss:
+		return true
+	}
+	return false
+}
+
+// DefaultSearchMode - mode for text when none is given:
+// wildcard if it has * or ?, gloss if it is in Latin script, otherwise exact
+func DefaultSearchMode(text string) SearchMode {
+	if strings.ContainsAny(text, wildcards) {
+		return SearchWildcard
+	}
+	for _, r := range text {
+		if r > unicode.MaxLatin1 {
+			return SearchExact
+		}
+	}
+	return SearchGloss
+}
+
+// SearchQuery - what to search for, and which page of results to return
+type SearchQuery struct {
+	Text   string
+	Mode   SearchMode
+	Offset int
+	Limit  int
+}
+
+// Validate - error if q would have to scan every entry
+func (q SearchQuery) Validate() error {
+	if q.Mode == SearchWildcard && strings.IndexAny(q.Text, wildcards) == 0 {
+		return errors.New("wildcard text must start with a character other than * or ?")
+	}
+	return nil
+}
+
+// SearchResult - one page of entries in sequence order
+type SearchResult struct {
+	Entries []Entry `json:"entries"`
+	// Total - number of entries matching, across every page,
+	// up to MaxSearchTotal
+	Total  int `json:"total"`
+	Offset int `json:"offset"`
+	Limit  int `json:"limit"`
+}
+
+// pattern - regular expression for q, understood by both Go and MongoDB
+func (q SearchQuery) pattern() string {
+	switch q.Mode {
+	case SearchPrefix:
+		return "^" + regexp.QuoteMeta(q.Text)
+	case SearchWildcard:
+		p := regexp.QuoteMeta(q.Text)
+		for _, star := range []string{`\*`, "＊"} {
+			p = strings.Replace(p, star, ".*", -1)
+		}
+		for _, question := range []string{`\?`, "？"} {
+			p = strings.Replace(p, question, ".", -1)
+		}
+		return "^" + p + "$"
+	case SearchGloss:
+		return `(?i)\b` + regexp.QuoteMeta(q.Text) + `\b`
+	}
+	return "^" + regexp.QuoteMeta(q.Text) + "$"
+}
+
+// filter - MongoDB query for q
+// Glosses are searched for as a phrase in the text index, which like
+// pattern matches whole words in any case
+func (q SearchQuery) filter() bson.M {
+	if q.Mode == SearchGloss {
+		return bson.M{"$text": bson.M{"$search": `"` + strings.Replace(q.Text, `"`, "", -1) + `"`}}
+	}
+	var match interface{} = q.Text
+	if q.Mode != SearchExact {
+		match = bson.M{"$regex": q.pattern()}
+	}
+	return bson.M{
+		"$or": bson.A{
+			bson.M{"readings": match},
+			bson.M{"kanji": match},
+		},
+	}
+}
+
+// Search - entries matching q, straight from the db
+// Results are not cached
+func (d *dictionary) Search(ctx context.Context, q SearchQuery) (SearchResult, error) {
+	result := SearchResult{Offset: q.Offset, Limit: q.Limit, Entries: make([]Entry, 0)}
+	raw, total, err := d.db.Search(ctx, q.filter(), int64(q.Offset), int64(q.Limit), MaxSearchTotal)
+	if err != nil {
+		d.log(ctx).Error(err)
+		return result, err
+	}
+	entries, err := decode(raw)
+	if err != nil {
+		d.log(ctx).Error(err)
+		return result, err
+	}
+	if entries != nil {
+		result.Entries = entries
+	}
+	result.Total = int(total)
+	return result, nil
+}
+
+// Entry - the entry with sequence number, with every meaning
+// Not cached
+func (d *dictionary) Entry(ctx context.Context, sequence int) (Entry, error) {
+	raw, err := d.db.Get(ctx, bson.M{"sequence": sequence})
+	if err != nil {
+		d.log(ctx).Error(err)
+		return Entry{}, err
+	}
+	entries, err := decode(raw)
+	if err != nil {
+		d.log(ctx).Error(err)
+		return Entry{}, err
+	}
+	if len(entries) == 0 {
+		return Entry{}, ErrNotFound
+	}
+	return entries[0], nil
+}
+
+// Entry - the entry with sequence number
+func (m *memory) Entry(ctx context.Context, sequence int) (Entry, error) {
+	if err := ctx.Err(); err != nil {
+		return Entry{}, err
+	}
+	i, ok := m.sequences[sequence]
+	if !ok {
+		return Entry{}, ErrNotFound
+	}
+	return m.entries[i], nil
+}
+
+// Search - entries matching q, in the order they were loaded
+func (m *memory) Search(ctx context.Context, q SearchQuery) (SearchResult, error) {
+	result := SearchResult{Offset: q.Offset, Limit: q.Limit, Entries: make([]Entry, 0)}
+	var matched []Entry
+	if q.Mode == SearchExact {
+		matched = m.find(q.Text)
+	} else {
+		re, err := regexp.Compile(q.pattern())
+		if err != nil {
+			return result, err
+		}
+		for i, entry := range m.entries {
+			if i%1000 == 0 {
+				if err := ctx.Err(); err != nil {
+					return result, err
+				}
+			}
+			if q.matches(re, entry) {
+				matched = append(matched, entry)
+			}
+		}
+	}
+	result.Total = len(matched)
+	if result.Total > MaxSearchTotal {
+		result.Total = MaxSearchTotal
+	}
+	if q.Offset < len(matched) {
+		end := q.Offset + q.Limit
+		if end > len(matched) {
+			end = len(matched)
+		}
+		result.Entries = matched[q.Offset:end]
+	}
+	return result, nil
+}
+
+// matches - true if re matches the part of entry searched by q
+func (q SearchQuery) matches(re *regexp.Regexp, entry Entry) bool {
+	if q.Mode == SearchGloss {
+		for _, m := range entry.Meanings {
+			if re.MatchString(m.Gloss) {
+				return true
+			}
+		}
+		return false
+	}
+	for _, forms := range [][]string{entry.Kanji, entry.Readings} {
+		for _, form := range forms {
+			if re.MatchString(form) {
+				return true
+			}
+		}
+	}
+	return false
+}
@@ -0,0 +1,115 @@
+package dictionary
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/mongodb/mongo-go-driver/bson"
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/zap"
+)
+
+func TestDefaultSearchMode(t *testing.T) {
+	assert.Equal(t, SearchExact, DefaultSearchMode("寒い"))
+	assert.Equal(t, SearchWildcard, DefaultSearchMode("寒*"))
+	assert.Equal(t, SearchWildcard, DefaultSearchMode("さむ？"))
+	assert.Equal(t, SearchGloss, DefaultSearchMode("to drink"))
+}
+
+func TestMemorySearch(t *testing.T) {
+	m := NewMemory([]Entry{
+		{Sequence: 1, Kanji: []string{"寒い"}, Readings: []string{"さむい"}, Meanings: []Meaning{{Gloss: "cold (e.g. weather)"}}},
+		{Sequence: 2, Kanji: []string{"寒気"}, Readings: []string{"さむけ"}, Meanings: []Meaning{{Gloss: "chill; the shivers"}}},
+		{Sequence: 3, Kanji: []string{"飲む"}, Readings: []string{"のむ"}, Meanings: []Meaning{{Gloss: "to drink"}}},
+		{Sequence: 4, Readings: []string{"ココア"}, Meanings: []Meaning{{Gloss: "cocoa; hot chocolate (drink)"}}},
+	}).(Searcher)
+	search := func(text string, mode SearchMode) []int {
+		res, err := m.Search(context.Background(), SearchQuery{Text: text, Mode: mode, Limit: 10})
+		assert.Nil(t, err)
+		sequences := make([]int, 0)
+		for _, e := range res.Entries {
+			sequences = append(sequences, e.Sequence)
+		}
+		return sequences
+	}
+
+	assert.Equal(t, []int{1}, search("さむい", SearchExact))
+	assert.Equal(t, []int{1, 2}, search("寒", SearchPrefix))
+	assert.Equal(t, []int{1, 2}, search("さむ?", SearchWildcard))
+	assert.Equal(t, []int{3, 4}, search("DRINK", SearchGloss))
+	// whole words only
+	assert.Empty(t, search("drin", SearchGloss))
+	// regexp characters are literal
+	assert.Empty(t, search(".*", SearchPrefix))
+
+	t.Run("pagination", func(t *testing.T) {
+		res, err := m.Search(context.Background(), SearchQuery{Text: "drink", Mode: SearchGloss, Offset: 1, Limit: 2})
+		assert.Nil(t, err)
+		assert.Equal(t, 2, res.Total)
+		assert.Equal(t, 4, res.Entries[0].Sequence)
+		assert.Equal(t, 1, len(res.Entries))
+
+		res, err = m.Search(context.Background(), SearchQuery{Text: "drink", Mode: SearchGloss, Offset: 10, Limit: 2})
+		assert.Nil(t, err)
+		assert.Equal(t, 2, res.Total)
+		assert.Empty(t, res.Entries)
+	})
+}
+
+func TestSearchQueryValidate(t *testing.T) {
+	assert.Nil(t, SearchQuery{Text: "寒*", Mode: SearchWildcard}.Validate())
+	assert.Nil(t, SearchQuery{Text: "*", Mode: SearchGloss}.Validate())
+	for _, text := range []string{"*", "?い", "＊い"} {
+		assert.NotNil(t, SearchQuery{Text: text, Mode: SearchWildcard}.Validate(), text)
+	}
+}
+
+func TestSearch(t *testing.T) {
+	t.Run("prefix filter", func(t *testing.T) {
+		db := &fakeDB{entries: []Entry{{Sequence: 1}}}
+		d := New(db, newFakeCache(), "1", zap.NewNop().Sugar()).(Searcher)
+		res, err := d.Search(context.Background(), SearchQuery{Text: "寒", Mode: SearchPrefix, Limit: 20})
+		assert.Nil(t, err)
+		assert.Equal(t, 1, res.Total)
+		assert.Equal(t, bson.M{"$or": bson.A{
+			bson.M{"readings": bson.M{"$regex": "^寒"}},
+			bson.M{"kanji": bson.M{"$regex": "^寒"}},
+		}}, db.query)
+	})
+
+	t.Run("gloss filter", func(t *testing.T) {
+		db := &fakeDB{}
+		d := New(db, newFakeCache(), "1", zap.NewNop().Sugar()).(Searcher)
+		_, err := d.Search(context.Background(), SearchQuery{Text: `hot "chocolate"`, Mode: SearchGloss, Limit: 20})
+		assert.Nil(t, err)
+		assert.Equal(t, bson.M{"$text": bson.M{"$search": `"hot chocolate"`}}, db.query)
+	})
+
+	t.Run("db error", func(t *testing.T) {
+		d := New(&fakeDB{err: errors.New("db down")}, newFakeCache(), "1", zap.NewNop().Sugar()).(Searcher)
+		_, err := d.Search(context.Background(), SearchQuery{Text: "寒い", Mode: SearchExact, Limit: 20})
+		assert.NotNil(t, err)
+	})
+}
+
+func TestEntry(t *testing.T) {
+	entries := []Entry{{Sequence: 1216250, Kanji: []string{"寒い"}}}
+	for name, r := range map[string]Repository{
+		"memory":  NewMemory(entries),
+		"mongodb": New(&fakeDB{entries: entries}, newFakeCache(), "1", zap.NewNop().Sugar()),
+	} {
+		t.Run(name, func(t *testing.T) {
+			e, err := r.(Searcher).Entry(context.Background(), 1216250)
+			assert.Nil(t, err)
+			assert.Equal(t, "寒い", e.Kanji[0])
+		})
+	}
+
+	t.Run("not found", func(t *testing.T) {
+		_, err := NewMemory(entries).(Searcher).Entry(context.Background(), 1)
+		assert.Equal(t, ErrNotFound, err)
+		_, err = New(&fakeDB{}, newFakeCache(), "1", zap.NewNop().Sugar()).(Searcher).Entry(context.Background(), 1)
+		assert.Equal(t, ErrNotFound, err)
+	})
+}
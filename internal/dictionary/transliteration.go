@@ -0,0 +1,32 @@
+package dictionary
+
+import (
+	"github.com/gilmoreg/seibiki/internal/transliterate"
+)
+
+// Transliteration - a token's reading and pronunciation in one system
+type Transliteration struct {
+	Reading string `json:"reading"`
+	Pron    string `json:"pron"`
+}
+
+// SetTransliterations - attach the Reading and Pron of each of the Word's
+// tokens written in each of systems; romaji readings have the long
+// vowels of Pron
+func (w Word) SetTransliterations(systems []transliterate.System) Word {
+	newTokens := make([]Token, 0)
+	for _, token := range w.Tokens {
+		if !token.IsPunctuation() && len(systems) > 0 {
+			token.Transliterations = make(map[string]Transliteration, len(systems))
+			for _, system := range systems {
+				token.Transliterations[string(system)] = Transliteration{
+					Reading: transliterate.ConvertReading(token.Reading, token.Pron, system),
+					Pron:    transliterate.Convert(token.Pron, system),
+				}
+			}
+		}
+		newTokens = append(newTokens, token)
+	}
+	w.Tokens = newTokens
+	return w
+}
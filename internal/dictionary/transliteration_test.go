@@ -0,0 +1,18 @@
+package dictionary
+
+import (
+	"testing"
+
+	"github.com/gilmoreg/seibiki/internal/transliterate"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSetTransliterations(t *testing.T) {
+	word := tokenize(t, "東京")[0].SetTransliterations([]transliterate.System{transliterate.Hiragana, transliterate.Hepburn})
+	tr := word.Tokens[0].Transliterations
+	assert.Equal(t, Transliteration{Reading: "とうきょう", Pron: "とーきょー"}, tr["hiragana"])
+	assert.Equal(t, Transliteration{Reading: "tōkyō", Pron: "tōkyō"}, tr["hepburn"])
+
+	word = tokenize(t, "勉強")[0].SetTransliterations([]transliterate.System{transliterate.Hepburn})
+	assert.Equal(t, "benkyō", word.Tokens[0].Transliterations["hepburn"].Reading)
+}
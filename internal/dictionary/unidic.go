@@ -0,0 +1,138 @@
+package dictionary
+
+// convertUni - fill in t from the features of a UniDic token
+// Features is [0-3] POS, [4] conjugation type, [5] conjugation form,
+// [6] lemma reading, [7] lemma, [8] surface, [9] pronunciation,
+// [10] base form, [11] base form pronunciation, [12-16] word origin etc.
+func convertUni(t Token, features []string) Token {
+	if len(features) <= 10 {
+		// Unknown words only have POS
+		return t
+	}
+	t.Base = features[10]
+	t.Lemma = features[7]
+	t.Pron = features[9]
+	t.Reading = kanaReading(features[6], features[9])
+	return t
+}
+
+// kanaReading - UniDic has no reading of the surface as it is spelt,
+// only its pronunciation, with ー for long vowels (トーキョー for 東京)
+// Where that is the only difference from the lemma's reading (トウキョウ),
+// the lemma's reading is used; otherwise the pronunciation is
+func kanaReading(lemmaReading, pron string) string {
+	l, p := []rune(lemmaReading), []rune(pron)
+	if len(l) != len(p) {
+		return pron
+	}
+	for i := range p {
+		if p[i] != l[i] && p[i] != 'ー' {
+			return pron
+		}
+	}
+	return lemmaReading
+}
+
+// edictMapping - EDict codes for each part of speech of d
+// An empty d is IPADIC
+func (d SysDic) edictMapping() map[string][]string {
+	if d == SysDicUni {
+		return uniToEdictMapping
+	}
+	return ipaToEdictMapping
+}
+
+// UniDic: https://clrd.ninjal.ac.jp/unidic/UNIDIC_manual.pdf
+// Most UniDic parts of speech take the codes of their IPADIC counterparts,
+// or of several where UniDic does not split the same way (e.g. IPADIC
+// has three kinds of 格助詞)
+var uniToEdictMapping = map[string][]string{
+	/*
+	  名詞 - Nouns
+	*/
+	"名詞,普通名詞,一般,*":    ipaToEdictMapping["名詞,一般,*,*"],
+	"名詞,普通名詞,サ変可能,*":  ipaToEdictMapping["名詞,サ変接続,*,*"],
+	"名詞,普通名詞,形状詞可能,*": ipaToEdictMapping["名詞,形容動詞語幹,*,*"],
+	// ex 「安心」「健康」
+	"名詞,普通名詞,サ変形状詞可能,*": union(
+		ipaToEdictMapping["名詞,サ変接続,*,*"],
+		ipaToEdictMapping["名詞,形容動詞語幹,*,*"],
+	),
+	"名詞,普通名詞,副詞可能,*": ipaToEdictMapping["名詞,副詞可能,*,*"],
+	// ex 「人」「組」
+	"名詞,普通名詞,助数詞可能,*": union(nounEDictTypes, []string{"&ctr;", "&suf;"}),
+	"名詞,固有名詞,一般,*":    ipaToEdictMapping["名詞,固有名詞,一般,*"],
+	"名詞,固有名詞,人名,一般":   ipaToEdictMapping["名詞,固有名詞,人名,一般"],
+	"名詞,固有名詞,人名,姓":    ipaToEdictMapping["名詞,固有名詞,人名,姓"],
+	"名詞,固有名詞,人名,名":    ipaToEdictMapping["名詞,固有名詞,人名,名"],
+	"名詞,固有名詞,地名,一般":   ipaToEdictMapping["名詞,固有名詞,地域,一般"],
+	"名詞,固有名詞,地名,国":    ipaToEdictMapping["名詞,固有名詞,地域,国"],
+	"名詞,数詞,*,*":       ipaToEdictMapping["名詞,数,*,*"],
+	// ex 「そう」in そうだ
+	"名詞,助動詞語幹,*,*": ipaToEdictMapping["名詞,特殊,助動詞語幹,*"],
+
+	// Pronouns are nouns in IPADIC
+	"代名詞,*,*,*": ipaToEdictMapping["名詞,代名詞,一般,*"],
+
+	/*
+	  形状詞 - Adjectival nouns (IPADIC 形容動詞語幹)
+	*/
+	"形状詞,一般,*,*": ipaToEdictMapping["名詞,形容動詞語幹,*,*"],
+	// ex 「堂々」「泰然」
+	"形状詞,タリ,*,*":    []string{"&adj-t;", "&adv-to;", "&adj-na;", "&n;"},
+	"形状詞,助動詞語幹,*,*": ipaToEdictMapping["名詞,特殊,助動詞語幹,*"],
+
+	/*
+	  Other content words
+	*/
+	"連体詞,*,*,*":       ipaToEdictMapping["連体詞,*,*,*"],
+	"副詞,*,*,*":        ipaToEdictMapping["副詞,一般,*,*"],
+	"接続詞,*,*,*":       ipaToEdictMapping["接続詞,*,*,*"],
+	"感動詞,一般,*,*":      ipaToEdictMapping["感動詞,*,*,*"],
+	"感動詞,フィラー,*,*":    ipaToEdictMapping["フィラー,*,*,*"],
+	"動詞,一般,*,*":       ipaToEdictMapping["動詞,自立,*,*"],
+	"動詞,非自立可能,*,*":    union(ipaToEdictMapping["動詞,自立,*,*"], ipaToEdictMapping["動詞,非自立,*,*"]),
+	"形容詞,一般,*,*":      ipaToEdictMapping["形容詞,自立,*,*"],
+	"形容詞,非自立可能,*,*":   union(ipaToEdictMapping["形容詞,自立,*,*"], ipaToEdictMapping["形容詞,非自立,*,*"]),
+	"助動詞,*,*,*":       ipaToEdictMapping["助動詞,*,*,*"],
+	"接頭辞,*,*,*":       union(ipaToEdictMapping["接頭詞,名詞接続,*,*"], ipaToEdictMapping["接頭詞,数接続,*,*"], ipaToEdictMapping["接頭詞,動詞接続,*,*"], ipaToEdictMapping["接頭詞,形容詞接続,*,*"]),
+	"記号,文字,*,*":       ipaToEdictMapping["記号,アルファベット,*,*"],
+	"接尾辞,形状詞的,*,*":    ipaToEdictMapping["名詞,接尾,形容動詞語幹,*"],
+	"接尾辞,動詞的,*,*":     ipaToEdictMapping["動詞,接尾,*,*"],
+	"接尾辞,形容詞的,*,*":    ipaToEdictMapping["形容詞,接尾,*,*"],
+	"接尾辞,名詞的,一般,*":    ipaToEdictMapping["名詞,接尾,一般,*"],
+	"接尾辞,名詞的,サ変可能,*":  ipaToEdictMapping["名詞,接尾,サ変接続,*"],
+	"接尾辞,名詞的,形状詞可能,*": ipaToEdictMapping["名詞,接尾,形容動詞語幹,*"],
+	"接尾辞,名詞的,副詞可能,*":  ipaToEdictMapping["名詞,接尾,副詞可能,*"],
+	"接尾辞,名詞的,助数詞,*":   ipaToEdictMapping["名詞,接尾,助数詞,*"],
+
+	/*
+	  助詞 - Particles
+	*/
+	"助詞,格助詞,*,*": union(
+		ipaToEdictMapping["助詞,格助詞,一般,*"],
+		ipaToEdictMapping["助詞,格助詞,引用,*"],
+		ipaToEdictMapping["助詞,格助詞,連語,*"],
+	),
+	"助詞,副助詞,*,*":  union(ipaToEdictMapping["助詞,副助詞,*,*"], ipaToEdictMapping["助詞,副詞化,*,*"]),
+	"助詞,係助詞,*,*":  ipaToEdictMapping["助詞,係助詞,*,*"],
+	"助詞,接続助詞,*,*": ipaToEdictMapping["助詞,接続助詞,*,*"],
+	"助詞,終助詞,*,*":  ipaToEdictMapping["助詞,終助詞,*,*"],
+	// ex 「の」in 行くのが
+	"助詞,準体助詞,*,*": union(ipaToEdictMapping["助詞,連体化,*,*"], []string{"&n;"}),
+}
+
+// union - codes in any of lists, each once, in order
+func union(lists ...[]string) []string {
+	seen := make(map[string]bool)
+	result := make([]string, 0)
+	for _, list := range lists {
+		for _, code := range list {
+			if !seen[code] {
+				seen[code] = true
+				result = append(result, code)
+			}
+		}
+	}
+	return result
+}
@@ -0,0 +1,45 @@
+package dictionary
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestUniDic(t *testing.T) {
+	tok, err := NewTokenizer([]SysDic{SysDicUni, SysDicIPA}, ModeSearch)
+	assert.Nil(t, err)
+	assert.Nil(t, tok.Check())
+
+	words, err := tok.Tokenize("東京は寒くなかった。", TokenizeOptions{})
+	assert.Nil(t, err)
+	assert.Equal(t, 4, len(words))
+	tokyo := words[0].Tokens[0]
+	assert.Equal(t, SysDicUni, tokyo.SysDic)
+	assert.Equal(t, []string{"名詞", "固有名詞", "地名", "一般"}, tokyo.POS)
+	assert.Equal(t, "東京", tokyo.Base)
+	assert.Equal(t, "トウキョウ", tokyo.Reading)
+	assert.Equal(t, "トーキョー", tokyo.Pron)
+	assert.Equal(t, "寒くなかった", words[2].Surface)
+	assert.Equal(t, []string{"寒い", "ない", "た"}, words[2].Bases())
+	assert.True(t, words[3].IsPunctuation())
+
+	cold := words[2].Tokens[0].SetEntries([]Entry{{
+		Kanji:    []string{"寒い"},
+		Meanings: []Meaning{{Gloss: "cold", PartOfSpeech: []string{"&adj-i;"}}, {Gloss: "coldness", PartOfSpeech: []string{"&n;"}}},
+	}})
+	assert.True(t, cold.Entries[0].Meanings[0].Match.Matched)
+	assert.False(t, cold.Entries[0].Meanings[1].Match.Matched)
+}
+
+func TestUniDicMapping(t *testing.T) {
+	for pos, codes := range uniToEdictMapping {
+		assert.NotEmpty(t, codes, pos)
+	}
+}
+
+func TestKanaReading(t *testing.T) {
+	assert.Equal(t, "トウキョウ", kanaReading("トウキョウ", "トーキョー"))
+	assert.Equal(t, "ワタシ", kanaReading("ワタクシ", "ワタシ"))
+	assert.Equal(t, "サムク", kanaReading("サムイ", "サムク"))
+}
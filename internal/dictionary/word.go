@@ -1,25 +1,50 @@
 package dictionary
 
 import (
-	"github.com/ikawaha/kagome.ipadic/tokenizer"
+	"context"
+	"strings"
+
+	"github.com/ikawaha/kagome/tokenizer"
 )
 
 // IsPunctuation - true if token is punctuation mark
+// (記号 in IPADIC; 補助記号, 記号 or 空白 in UniDic)
 func (t Token) IsPunctuation() bool {
-	return t.POS[0] == "記号"
+	switch t.POS[0] {
+	case "記号", "補助記号", "空白":
+		return true
+	}
+	return false
 }
 
-// Convert - create Token from kagome token
-// Features is [0-5] POS (0-4 IPA codes, unsure what 5 is)
-// [6] base form, [7] reading, [8] pronounciation
-func Convert(t tokenizer.Token) Token {
+// Convert - create Token from kagome token analysed with d
+// Tokens without features, such as the characters extended mode splits
+// unknown words into, get "*" for every POS level and base form
+func Convert(t tokenizer.Token, d SysDic) Token {
 	features := t.Features()
 	result := Token{
 		ID:      t.ID,
 		Class:   t.Class.String(),
 		Surface: t.Surface,
-		POS:     features[0:4],
-		Base:    features[6],
+		POS:     []string{"*", "*", "*", "*"},
+		Base:    "*",
+	}
+	if d != SysDicIPA {
+		result.SysDic = d
+	}
+	if t.Class == tokenizer.USER {
+		return convertUser(result, features)
+	}
+	if len(features) >= 4 {
+		result.POS = features[0:4]
+	}
+	if d == SysDicUni {
+		return convertUni(result, features)
+	}
+	// Features is [0-5] POS (0-4 IPA codes, unsure what 5 is)
+	// [6] base form, [7] reading, [8] pronounciation
+	if len(features) > 6 {
+		result.Base = features[6]
 	}
 	if len(features) > 7 {
 		result.Reading = features[7]
@@ -30,24 +55,111 @@ func Convert(t tokenizer.Token) Token {
 	return result
 }
 
+// convertUser - fill in t from the features of a user dictionary token:
+// [0] part of speech, [1] segmentation and [2] readings, split by "/"
+// The whole surface is the base form, so it is looked up as one word
+func convertUser(t Token, features []string) Token {
+	t.Base = t.Surface
+	if len(features) == 3 {
+		t.POS[0] = features[0]
+		t.Reading = strings.Replace(features[2], "/", "", -1)
+		t.Pron = t.Reading
+	}
+	return t
+}
+
 // GetEntries - fetch entries for Token from DictionaryRepository
-func (t Token) GetEntries(r Repository) Token {
+// On error the Token is returned unchanged, without entries
+func (t Token) GetEntries(ctx context.Context, r Repository) (Token, error) {
 	if t.IsPunctuation() {
-		return t
+		return t, nil
 	}
-	entries, err := r.Lookup(t.Base)
+	entries, err := r.Lookup(ctx, t.Base)
 	if err != nil {
-		return Token{}
+		return t, err
+	}
+	return t.SetEntries(entries), nil
+}
+
+// SetEntries - attach entries to Token, marking whether each meaning
+// matches its POS; ApplyStrictness decides what to do with those that do not
+func (t Token) SetEntries(entries []Entry) Token {
+	if t.IsPunctuation() {
+		return t
 	}
 	if len(entries) > 0 {
 		t.Entries = make([]Entry, 0)
 		for _, entry := range entries {
-			entry.Meanings = Filter(t.POS, entry.Meanings)
-			if len(entry.Meanings) > 0 {
-				t.Entries = append(t.Entries, entry)
+			entry.Meanings = t.annotate(entry)
+			t.Entries = append(t.Entries, entry)
+		}
+	}
+	return t
+}
+
+// annotate - entry's meanings, marked with whether they match the POS
+// User dictionary tokens have no POS that maps to EDict codes, and user
+// glossary meanings need not have any codes, so neither is filtered
+func (t Token) annotate(entry Entry) []Meaning {
+	reason := ""
+	switch {
+	case entry.User:
+		reason = "user glossary entry"
+	case t.Class == tokenizer.USER.String():
+		reason = "user dictionary token"
+	default:
+		return annotate(t.SysDic.edictMapping(), t.POS, entry.Meanings)
+	}
+	res := make([]Meaning, 0, len(entry.Meanings))
+	for _, m := range entry.Meanings {
+		m.Match = &MeaningMatch{Matched: true, Reason: reason}
+		res = append(res, m)
+	}
+	return res
+}
+
+// ApplyStrictness - drop or reorder meanings that do not match the POS
+// Unless s is StrictnessOff, entries and meanings are also ranked,
+// most likely first. StrictnessStrict also drops entries not read the
+// way the token is, as long as one of them is
+func (t Token) ApplyStrictness(s Strictness) Token {
+	if s == StrictnessOff || len(t.Entries) == 0 {
+		return t
+	}
+	matched, unmatched := make([]Entry, 0), make([]Entry, 0)
+	for _, entry := range t.Entries {
+		fit, misfit := make([]Meaning, 0), make([]Meaning, 0)
+		for _, m := range entry.Meanings {
+			if m.matched() {
+				fit = append(fit, m)
+			} else {
+				misfit = append(misfit, m)
 			}
 		}
+		t.rank(fit)
+		t.rank(misfit)
+		if s == StrictnessStrict {
+			if len(fit) > 0 {
+				entry.Meanings = fit
+				entry.Score = t.entryScore(entry)
+				matched = append(matched, entry)
+			}
+			continue
+		}
+		entry.Meanings = append(fit, misfit...)
+		entry.Score = t.entryScore(entry)
+		if len(fit) > 0 {
+			matched = append(matched, entry)
+		} else {
+			unmatched = append(unmatched, entry)
+		}
+	}
+	if s == StrictnessStrict {
+		matched = t.restrictToReading(matched)
 	}
+	rankEntries(matched)
+	rankEntries(unmatched)
+	t.Entries = append(matched, unmatched...)
 	return t
 }
 
@@ -65,22 +177,205 @@ func (w Word) IsPunctuation() bool {
 	return w.Tokens[0].IsPunctuation()
 }
 
+// Bases - base forms of the Word's tokens that need a dictionary lookup
+func (w Word) Bases() []string {
+	bases := make([]string, 0)
+	if w.IsPunctuation() {
+		return bases
+	}
+	for _, token := range w.Tokens {
+		// UNKNOWN tokens have no base form
+		if !token.IsPunctuation() && token.Base != "*" {
+			bases = append(bases, token.Base)
+		}
+	}
+	return bases
+}
+
+// Bases - base forms of every token in words that need a dictionary lookup
+func Bases(words []Word) []string {
+	bases := make([]string, 0)
+	for _, word := range words {
+		bases = append(bases, word.Bases()...)
+	}
+	return bases
+}
+
 // GetEntries - fetch entries for tokens from DictionaryRepository
-func (w Word) GetEntries(r Repository) Word {
+// On error the Word keeps whatever entries were found before the failure
+func (w Word) GetEntries(ctx context.Context, r Repository) (Word, error) {
+	if w.IsPunctuation() {
+		return w, nil
+	}
+	entries, err := r.LookupMany(ctx, w.Bases())
+	return w.SetEntries(entries), err
+}
+
+// SetEntries - attach entries already fetched for each token's base form
+func (w Word) SetEntries(entries map[string][]Entry) Word {
 	if w.IsPunctuation() {
 		return w
 	}
 
-	// If no result for word surface, look up each token individually
+	// Each token is looked up by its base form; expressions spanning
+	// several words are joined afterwards by JoinPhrases
+	newTokens := make([]Token, 0)
+	for _, token := range w.Tokens {
+		newTokens = append(newTokens, token.SetEntries(entries[token.Base]))
+	}
+	w.Tokens = newTokens
+
+	return w
+}
+
+// missing - true if the direct lookup found nothing matching this token
+func (t Token) missing() bool {
+	if t.IsPunctuation() {
+		return false
+	}
+	for _, entry := range t.Entries {
+		for _, m := range entry.Meanings {
+			if m.matched() {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+// spans - surfaces of the Word starting at token i, longest first,
+// so a conjugated form split across tokens is tried as a whole
+func (w Word) spans(i int) []string {
+	spans := make([]string, 0)
+	for j := len(w.Tokens); j > i; j-- {
+		surface := ""
+		for _, token := range w.Tokens[i:j] {
+			surface += token.Surface
+		}
+		spans = append(spans, surface)
+	}
+	return spans
+}
+
+const (
+	// maxTokenCandidates - most dictionary forms tried for one token
+	maxTokenCandidates = 20
+	// maxCandidates - most dictionary forms tried in one lookup
+	maxCandidates = 200
+)
+
+// DeinflectionCandidates - possible dictionary forms for tokens
+// the direct lookup missed, at most maxTokenCandidates per token
+// Longer spans and shorter inflection chains are kept first
+func (w Word) DeinflectionCandidates() []string {
+	candidates := make([]string, 0)
+	if w.IsPunctuation() {
+		return candidates
+	}
+	for i, token := range w.Tokens {
+		if !token.missing() {
+			continue
+		}
+		seen := make(map[string]bool)
+	spans:
+		for _, span := range w.spans(i) {
+			for _, d := range Deinflect(span) {
+				if seen[d.Word] {
+					continue
+				}
+				if len(seen) == maxTokenCandidates {
+					break spans
+				}
+				seen[d.Word] = true
+				candidates = append(candidates, d.Word)
+			}
+		}
+	}
+	return candidates
+}
+
+// DeinflectionCandidates - distinct possible dictionary forms for every
+// token in words the direct lookup missed, at most maxCandidates in all
+// so that one lookup cannot send the repository an unbounded query
+func DeinflectionCandidates(words []Word) []string {
+	candidates := make([]string, 0)
+	seen := make(map[string]bool)
+	for _, word := range words {
+		for _, candidate := range word.DeinflectionCandidates() {
+			if seen[candidate] {
+				continue
+			}
+			if len(candidates) == maxCandidates {
+				return candidates
+			}
+			seen[candidate] = true
+			candidates = append(candidates, candidate)
+		}
+	}
+	return candidates
+}
+
+// ApplyStrictness - drop or reorder meanings that do not match
+// the POS of each token, and rank the entries of a joined phrase
+func (w Word) ApplyStrictness(s Strictness) Word {
 	newTokens := make([]Token, 0)
 	for _, token := range w.Tokens {
-		newTokens = append(newTokens, token.GetEntries(r))
+		newTokens = append(newTokens, token.ApplyStrictness(s))
 	}
 	w.Tokens = newTokens
+	if s != StrictnessOff && len(w.Entries) > 0 {
+		w.Entries = w.rankPhrase(s)
+	}
+	return w
+}
 
+// SetDeinflectedEntries - attach entries found for deinflected forms
+// to tokens the direct lookup missed, recording the inflections undone
+// Longer spans and shorter inflection chains win
+func (w Word) SetDeinflectedEntries(entries map[string][]Entry) Word {
+	if w.IsPunctuation() {
+		return w
+	}
+	newTokens := make([]Token, 0)
+	for i, token := range w.Tokens {
+		if token.missing() {
+			token = token.deinflect(w.spans(i), entries)
+		}
+		newTokens = append(newTokens, token)
+	}
+	w.Tokens = newTokens
 	return w
 }
 
+// deinflect - attach the first deinflected form of spans with matching entries
+func (t Token) deinflect(spans []string, entries map[string][]Entry) Token {
+	for _, span := range spans {
+		for _, d := range Deinflect(span) {
+			matched := make([]Entry, 0)
+			for _, entry := range entries[d.Word] {
+				meanings := make([]Meaning, 0)
+				for _, m := range entry.Meanings {
+					if d.Matches(m) {
+						m.Match = &MeaningMatch{Matched: true, Reason: "deinflected to " + d.Word}
+						meanings = append(meanings, m)
+					}
+				}
+				if len(meanings) > 0 {
+					entry.Meanings = meanings
+					matched = append(matched, entry)
+				}
+			}
+			if len(matched) > 0 {
+				t.Entries = matched
+				t.Deinflected = d.Word
+				t.Inflection = d.Inflection
+				return t
+			}
+		}
+	}
+	return t
+}
+
 // Token - kagome token plus dictionary entries
 type Token struct {
 	ID      int      `json:"id"`
@@ -90,7 +385,25 @@ type Token struct {
 	Base    string   `json:"base"`
 	Reading string   `json:"reading"`
 	Pron    string   `json:"pron"`
-	Entries []Entry  `json:"entries"`
+	// Lemma - UniDic's headword, shared by variant spellings
+	// e.g. 行く for いく; empty with IPADIC
+	Lemma string `json:"lemma,omitempty"`
+	// SysDic - dictionary the token was analysed with, which decides
+	// the part of speech scheme of POS; empty for IPADIC
+	SysDic  SysDic  `json:"sysdic,omitempty"`
+	Entries []Entry `json:"entries"`
+	// Deinflected - dictionary form the entries were found under,
+	// when Base had none
+	Deinflected string `json:"deinflected,omitempty"`
+	// Inflection - inflections undone to reach Deinflected, outermost first
+	// e.g. ["past", "negative"]
+	Inflection []string `json:"inflection,omitempty"`
+	// Furigana - the surface split into kana and kanji runs,
+	// each kanji run with its reading; only set when requested
+	Furigana []Ruby `json:"furigana,omitempty"`
+	// Transliterations - Reading and Pron in each requested system,
+	// keyed by system name e.g. "hepburn"
+	Transliterations map[string]Transliteration `json:"transliterations,omitempty"`
 }
 
 // Entry - dictionary entry
@@ -99,6 +412,14 @@ type Entry struct {
 	Kanji    []string  `json:"kanji"`
 	Readings []string  `json:"readings"`
 	Meanings []Meaning `json:"meanings"`
+	// Priority - JMdict priority tags of any of the entry's forms
+	// e.g. ["ichi1", "news1", "nf12"]
+	Priority []string `json:"priority,omitempty"`
+	// Score - how likely the entry is meant by the token it was
+	// looked up for; never stored
+	Score float64 `json:"score,omitempty" bson:"-"`
+	// User - entry comes from the user glossary rather than JMdict
+	User bool `json:"user,omitempty" bson:"-"`
 }
 
 // Meaning - an English meaning with its part of speech
@@ -106,10 +427,24 @@ type Meaning struct {
 	Gloss        string   `json:"gloss"`
 	PartOfSpeech []string `json:"partofspeech"`
 	Misc         []string `json:"misc"`
+	// Match - whether the meaning fits the token it was looked up for;
+	// never stored
+	Match *MeaningMatch `json:"match,omitempty" bson:"-"`
+}
+
+// MeaningMatch - whether a meaning fits a token's part of speech, and why
+type MeaningMatch struct {
+	Matched bool   `json:"matched"`
+	Reason  string `json:"reason"`
 }
 
 // Word - set of one or more Tokens comprising a single unit
 type Word struct {
 	Surface string  `json:"surface"`
 	Tokens  []Token `json:"tokens"`
+	// Base - dictionary form of a phrase joined from several words
+	// e.g. 気にする for 気にしない
+	Base string `json:"base,omitempty"`
+	// Entries - entries for the phrase as a whole; each token keeps its own
+	Entries []Entry `json:"entries,omitempty"`
 }
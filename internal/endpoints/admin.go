@@ -0,0 +1,86 @@
+package endpoints
+
+import (
+	"context"
+	"crypto/subtle"
+	"errors"
+	"net/http"
+	"strings"
+
+	"github.com/gilmoreg/seibiki/internal/dictionary"
+	"github.com/go-kit/kit/endpoint"
+	httptransport "github.com/go-kit/kit/transport/http"
+)
+
+var errUnauthorized = errors.New("unauthorized")
+
+// FlushHandler - new http.Handler that drops every cached dictionary entry
+// Requests must carry "Authorization: Bearer <token>"
+func FlushHandler(inv dictionary.Invalidator, token string) *httptransport.Server {
+	return httptransport.NewServer(
+		createFlushEndpoint(inv),
+		decodeAdminRequest(token),
+		encodeResponse,
+		httptransport.ServerErrorEncoder(encodeError),
+	)
+}
+
+func createFlushEndpoint(inv dictionary.Invalidator) endpoint.Endpoint {
+	return func(ctx context.Context, request interface{}) (response interface{}, err error) {
+		n, err := inv.Invalidate(ctx)
+		if err != nil {
+			return nil, err
+		}
+		return flushResponse{Flushed: n}, nil
+	}
+}
+
+// ReloadFunc - reload the user dictionary and glossary, returning how
+// many words and entries each now has
+type ReloadFunc func(ctx context.Context) (userDictionary, glossary int, err error)
+
+// ReloadHandler - new http.Handler that rereads the user dictionary and
+// glossary files; requests must carry the admin token as for FlushHandler
+func ReloadHandler(reload ReloadFunc, token string) *httptransport.Server {
+	return httptransport.NewServer(
+		createReloadEndpoint(reload),
+		decodeAdminRequest(token),
+		encodeResponse,
+		httptransport.ServerErrorEncoder(encodeError),
+	)
+}
+
+func createReloadEndpoint(reload ReloadFunc) endpoint.Endpoint {
+	return func(ctx context.Context, request interface{}) (response interface{}, err error) {
+		words, entries, err := reload(ctx)
+		if err != nil {
+			return nil, err
+		}
+		return reloadResponse{UserDictionary: words, Glossary: entries}, nil
+	}
+}
+
+// decodeAdminRequest - reject requests without the admin token
+// An empty token disables admin requests entirely
+func decodeAdminRequest(token string) httptransport.DecodeRequestFunc {
+	return func(_ context.Context, r *http.Request) (interface{}, error) {
+		auth := r.Header.Get("Authorization")
+		if token == "" || !strings.HasPrefix(auth, "Bearer ") {
+			return nil, errUnauthorized
+		}
+		given := strings.TrimPrefix(auth, "Bearer ")
+		if subtle.ConstantTimeCompare([]byte(given), []byte(token)) != 1 {
+			return nil, errUnauthorized
+		}
+		return nil, nil
+	}
+}
+
+type flushResponse struct {
+	Flushed int `json:"flushed"`
+}
+
+type reloadResponse struct {
+	UserDictionary int `json:"user_dictionary"`
+	Glossary       int `json:"glossary"`
+}
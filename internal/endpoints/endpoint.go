@@ -3,55 +3,235 @@ package endpoints
 import (
 	"context"
 	"encoding/json"
-	"errors"
+	"fmt"
+	"io"
 	"io/ioutil"
 	"net/http"
+	"unicode/utf8"
 
+	"github.com/gilmoreg/seibiki/internal/dictionary"
 	"github.com/gilmoreg/seibiki/internal/service"
+	"github.com/gilmoreg/seibiki/internal/transliterate"
 	"github.com/go-kit/kit/endpoint"
 	httptransport "github.com/go-kit/kit/transport/http"
 )
 
+// Limits - largest lookup request accepted; 0 for no limit
+type Limits struct {
+	// MaxBodyBytes - size of the JSON body
+	MaxBodyBytes int64
+	// MaxQueryLength - characters in the query
+	MaxQueryLength int
+}
+
 // Handler - new http.Handler
-func Handler(svc service.LookupService) *httptransport.Server {
+func Handler(svc service.LookupService, limits Limits, in Instruments) *httptransport.Server {
 	return httptransport.NewServer(
-		createEndpoint(svc),
-		decodeQueryRequest,
+		createEndpoint(svc, in),
+		decodeQueryRequest(limits),
 		encodeResponse,
+		httptransport.ServerErrorEncoder(encodeError),
 	)
 }
 
-func createEndpoint(svc service.LookupService) endpoint.Endpoint {
+func createEndpoint(svc service.LookupService, in Instruments) endpoint.Endpoint {
 	return func(ctx context.Context, request interface{}) (response interface{}, err error) {
-		req := request.(queryRequest).Query
-		return svc.Lookup(req), nil
+		req := request.(queryRequest)
+		words, err := svc.Lookup(req.context(ctx), req.Query)
+		if p, ok := err.(service.PartialError); ok {
+			in.observeTokens(countTokens(p.Words))
+			p.Words = req.decorateAll(p.Words, in)
+			return nil, p
+		}
+		if err != nil {
+			return nil, err
+		}
+		in.observeTokens(countTokens(words))
+		words = req.decorateAll(words, in)
+		if req.Furigana {
+			return furiganaResponse{Words: words, HTML: dictionary.RubyHTML(words)}, nil
+		}
+		return words, nil
 	}
 }
 
-func decodeQueryRequest(_ context.Context, r *http.Request) (interface{}, error) {
-	var query queryRequest
-	if r.Body == nil {
-		return nil, errors.New("missing body")
+// decorate - filter meanings and add the per-token output
+// asked for in the request, counting the meanings kept and dropped in in
+func (req queryRequest) decorate(word dictionary.Word, in Instruments) dictionary.Word {
+	strictness := req.Strictness
+	if strictness == "" {
+		strictness = dictionary.StrictnessStrict
 	}
-	body, err := ioutil.ReadAll(r.Body)
-	if err != nil {
-		return nil, err
+	filtered := word.ApplyStrictness(strictness)
+	in.countMeanings(word, filtered)
+	word = filtered
+	if len(req.Transliterate) > 0 {
+		word = word.SetTransliterations(req.Transliterate)
+	}
+	if req.Furigana {
+		word = word.SetFurigana()
 	}
-	defer r.Body.Close()
-	err = json.Unmarshal(body, &query)
+	return word
+}
+
+func (req queryRequest) decorateAll(words []dictionary.Word, in Instruments) []dictionary.Word {
+	result := make([]dictionary.Word, 0)
+	for _, word := range words {
+		result = append(result, req.decorate(word, in))
+	}
+	return result
+}
+
+// context - ctx carrying the tokenizer options asked for
+func (req queryRequest) context(ctx context.Context) context.Context {
+	return service.WithTokenizeOptions(ctx, dictionary.TokenizeOptions{SysDic: req.Tokenizer, Mode: req.Mode})
+}
+
+// decodeQueryRequest - read a queryRequest, with a TooLargeError
+// if the body or query is over limits
+func decodeQueryRequest(limits Limits) httptransport.DecodeRequestFunc {
+	return func(_ context.Context, r *http.Request) (interface{}, error) {
+		var query queryRequest
+		if r.Body == nil {
+			return nil, service.BadInputError{Reason: "missing body"}
+		}
+		defer r.Body.Close()
+		body, err := readBody(r, limits.MaxBodyBytes)
+		if err != nil {
+			return nil, err
+		}
+		err = json.Unmarshal(body, &query)
+		if err != nil {
+			return nil, service.BadInputError{Reason: err.Error()}
+		}
+		if limits.MaxQueryLength > 0 && utf8.RuneCountInString(query.Query) > limits.MaxQueryLength {
+			return nil, service.TooLargeError{Reason: fmt.Sprintf("query is over %d characters", limits.MaxQueryLength)}
+		}
+		if err := query.validate(); err != nil {
+			return nil, err
+		}
+		return query, nil
+	}
+}
+
+// readBody - the request body, unless it is over max bytes
+// Stops reading as soon as it is, rather than buffering the rest
+func readBody(r *http.Request, max int64) ([]byte, error) {
+	if max <= 0 {
+		return ioutil.ReadAll(r.Body)
+	}
+	tooLarge := service.TooLargeError{Reason: fmt.Sprintf("body is over %d bytes", max)}
+	if r.ContentLength > max {
+		return nil, tooLarge
+	}
+	body, err := ioutil.ReadAll(io.LimitReader(r.Body, max+1))
 	if err != nil {
 		return nil, err
 	}
-	return query, nil
+	if int64(len(body)) > max {
+		return nil, tooLarge
+	}
+	return body, nil
+}
+
+// validate - BadInputError for options that are not supported
+func (query queryRequest) validate() error {
+	if query.Strictness != "" && !query.Strictness.Valid() {
+		return service.BadInputError{Reason: fmt.Sprintf("unknown strictness %q", query.Strictness)}
+	}
+	for _, system := range query.Transliterate {
+		if !system.Valid() {
+			return service.BadInputError{Reason: fmt.Sprintf("unknown transliteration %q", system)}
+		}
+	}
+	if query.Tokenizer != "" && !query.Tokenizer.Valid() {
+		return service.BadInputError{Reason: fmt.Sprintf("unknown tokenizer %q", query.Tokenizer)}
+	}
+	if query.Mode != "" && !query.Mode.Valid() {
+		return service.BadInputError{Reason: fmt.Sprintf("unknown mode %q", query.Mode)}
+	}
+	return nil
 }
 
 func encodeResponse(_ context.Context, w http.ResponseWriter, response interface{}) error {
+	setHeaders(w)
+	return json.NewEncoder(w).Encode(response)
+}
+
+// encodeError - map service errors to a status code and JSON error body
+// Partial results are still sent, under "words", with a 207
+func encodeError(_ context.Context, err error, w http.ResponseWriter) {
+	status, res := errorStatus(err)
+	setHeaders(w)
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(res)
+}
+
+// errorStatus - status code and error body for err
+func errorStatus(err error) (int, errorResponse) {
+	res := errorResponse{Code: "internal", Error: err.Error()}
+	status := http.StatusInternalServerError
+	switch e := err.(type) {
+	case service.BadInputError:
+		status, res.Code = http.StatusBadRequest, "bad_input"
+	case service.TooLargeError:
+		status, res.Code = http.StatusRequestEntityTooLarge, "too_large"
+	case service.UnavailableError:
+		status, res.Code = http.StatusServiceUnavailable, "backend_unavailable"
+	case service.PartialError:
+		status, res.Code = http.StatusMultiStatus, "partial_result"
+		res.Words = e.Words
+	default:
+		switch err {
+		case context.DeadlineExceeded:
+			status, res.Code = http.StatusGatewayTimeout, "timeout"
+		case context.Canceled:
+			status, res.Code = statusClientClosedRequest, "cancelled"
+		case errUnauthorized:
+			status, res.Code = http.StatusUnauthorized, "unauthorized"
+		case dictionary.ErrNotFound:
+			status, res.Code = http.StatusNotFound, "not_found"
+		}
+	}
+	return status, res
+}
+
+func setHeaders(w http.ResponseWriter) {
 	w.Header().Set("Content-Type", "application/json")
 	w.Header().Set("Access-Control-Allow-Origin", "*")
-	w.Header().Set("Access-Control-Allow-Methods", "OPTIONS, POST")
-	return json.NewEncoder(w).Encode(response)
+	w.Header().Set("Access-Control-Allow-Methods", "OPTIONS, GET, POST")
 }
 
+// statusClientClosedRequest - nginx convention for a client that hung up
+// before the response was ready
+const statusClientClosedRequest = 499
+
 type queryRequest struct {
 	Query string `json:"query"`
+	// Furigana - add ruby to each token and render the query as HTML
+	Furigana bool `json:"furigana"`
+	// Transliterate - systems to write each token's reading and pron in
+	// e.g. ["hiragana", "hepburn"]
+	Transliterate []transliterate.System `json:"transliterate"`
+	// Strictness - what to do with meanings that do not fit a token's
+	// part of speech: "strict" (default) drops them, "ranked" puts them
+	// last and "off" leaves them in place
+	Strictness dictionary.Strictness `json:"strictness"`
+	// Tokenizer - system dictionary to tokenize with, "ipadic" or
+	// "unidic"; defaults to the server's, and must be one it loaded
+	Tokenizer dictionary.SysDic `json:"tokenizer"`
+	// Mode - how to segment the query: "normal", "search" or "extended"
+	Mode dictionary.Mode `json:"mode"`
+}
+
+// furiganaResponse - lookup result when furigana are requested
+type furiganaResponse struct {
+	Words []dictionary.Word `json:"words"`
+	HTML  string            `json:"html"`
+}
+
+type errorResponse struct {
+	Code  string            `json:"code"`
+	Error string            `json:"error"`
+	Words []dictionary.Word `json:"words,omitempty"`
 }
@@ -0,0 +1,114 @@
+package endpoints
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/gilmoreg/seibiki/internal/dictionary"
+	"github.com/gilmoreg/seibiki/internal/service"
+	"github.com/go-kit/kit/endpoint"
+	httptransport "github.com/go-kit/kit/transport/http"
+	"github.com/gorilla/mux"
+)
+
+const (
+	defaultSearchLimit = 20
+	maxSearchLimit     = 100
+)
+
+// SearchHandler - search entries by kanji, reading or English gloss
+// GET ?q=&mode=exact|prefix|wildcard|gloss&offset=&limit=
+// Without mode, q is searched as a wildcard if it has * or ?,
+// as a gloss if it is in Latin script, and exactly otherwise
+func SearchHandler(s dictionary.Searcher) *httptransport.Server {
+	return httptransport.NewServer(
+		createSearchEndpoint(s),
+		decodeSearchRequest,
+		encodeResponse,
+		httptransport.ServerErrorEncoder(encodeError),
+	)
+}
+
+func createSearchEndpoint(s dictionary.Searcher) endpoint.Endpoint {
+	return func(ctx context.Context, request interface{}) (interface{}, error) {
+		res, err := s.Search(ctx, request.(dictionary.SearchQuery))
+		if err != nil {
+			if ctxErr := ctx.Err(); ctxErr != nil {
+				return nil, ctxErr
+			}
+			return nil, service.UnavailableError{Err: err}
+		}
+		return res, nil
+	}
+}
+
+func decodeSearchRequest(_ context.Context, r *http.Request) (interface{}, error) {
+	params := r.URL.Query()
+	q := dictionary.SearchQuery{
+		Text:  strings.TrimSpace(params.Get("q")),
+		Mode:  dictionary.SearchMode(params.Get("mode")),
+		Limit: defaultSearchLimit,
+	}
+	if q.Text == "" {
+		return nil, service.BadInputError{Reason: "q is empty"}
+	}
+	if q.Mode == "" {
+		q.Mode = dictionary.DefaultSearchMode(q.Text)
+	}
+	if !q.Mode.Valid() {
+		return nil, service.BadInputError{Reason: fmt.Sprintf("unknown mode %q", q.Mode)}
+	}
+	if err := q.Validate(); err != nil {
+		return nil, service.BadInputError{Reason: err.Error()}
+	}
+	var err error
+	if s := params.Get("offset"); s != "" {
+		q.Offset, err = strconv.Atoi(s)
+		if err != nil || q.Offset < 0 || q.Offset >= dictionary.MaxSearchTotal {
+			return nil, service.BadInputError{Reason: fmt.Sprintf("offset must be 0 to %d", dictionary.MaxSearchTotal-1)}
+		}
+	}
+	if s := params.Get("limit"); s != "" {
+		q.Limit, err = strconv.Atoi(s)
+		if err != nil || q.Limit < 1 || q.Limit > maxSearchLimit {
+			return nil, service.BadInputError{Reason: fmt.Sprintf("limit must be 1 to %d", maxSearchLimit)}
+		}
+	}
+	return q, nil
+}
+
+// EntryHandler - a single entry by JMdict sequence number,
+// with every meaning, for /entries/{sequence}
+func EntryHandler(s dictionary.Searcher) *httptransport.Server {
+	return httptransport.NewServer(
+		createEntryEndpoint(s),
+		decodeEntryRequest,
+		encodeResponse,
+		httptransport.ServerErrorEncoder(encodeError),
+	)
+}
+
+func createEntryEndpoint(s dictionary.Searcher) endpoint.Endpoint {
+	return func(ctx context.Context, request interface{}) (interface{}, error) {
+		entry, err := s.Entry(ctx, request.(int))
+		if err != nil && err != dictionary.ErrNotFound {
+			if ctxErr := ctx.Err(); ctxErr != nil {
+				return nil, ctxErr
+			}
+			return nil, service.UnavailableError{Err: err}
+		}
+		return entry, err
+	}
+}
+
+func decodeEntryRequest(_ context.Context, r *http.Request) (interface{}, error) {
+	s := mux.Vars(r)["sequence"]
+	sequence, err := strconv.Atoi(s)
+	if err != nil || sequence < 1 {
+		return nil, service.BadInputError{Reason: fmt.Sprintf("invalid sequence %q", s)}
+	}
+	return sequence, nil
+}
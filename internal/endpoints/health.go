@@ -0,0 +1,49 @@
+package endpoints
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"time"
+
+	"github.com/gilmoreg/seibiki/internal/health"
+	"github.com/go-kit/kit/endpoint"
+	httptransport "github.com/go-kit/kit/transport/http"
+)
+
+// readyTimeout - how long each dependency has to answer /readyz
+const readyTimeout = 2 * time.Second
+
+// LiveHandler - 200 while the process is serving requests, for /healthz
+// Checks no dependencies, so a slow database never gets the process restarted
+func LiveHandler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		setHeaders(w)
+		json.NewEncoder(w).Encode(map[string]string{"status": "ok"})
+	})
+}
+
+// ReadyHandler - status and latency of each dependency, for /readyz
+// 503 when a required one is down, so traffic is held back until it is up
+func ReadyHandler(checks []health.Check) *httptransport.Server {
+	return httptransport.NewServer(
+		createReadyEndpoint(checks),
+		func(context.Context, *http.Request) (interface{}, error) { return nil, nil },
+		encodeReadyResponse,
+		httptransport.ServerErrorEncoder(encodeError),
+	)
+}
+
+func createReadyEndpoint(checks []health.Check) endpoint.Endpoint {
+	return func(ctx context.Context, _ interface{}) (interface{}, error) {
+		return health.Run(ctx, checks, readyTimeout), nil
+	}
+}
+
+func encodeReadyResponse(_ context.Context, w http.ResponseWriter, response interface{}) error {
+	setHeaders(w)
+	if !response.(health.Report).Ready {
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}
+	return json.NewEncoder(w).Encode(response)
+}
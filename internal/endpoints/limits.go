@@ -0,0 +1,74 @@
+package endpoints
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/gilmoreg/seibiki/internal/ratelimit"
+	"github.com/gorilla/mux"
+)
+
+// LimitRequests - 429 with Retry-After once a client has used up its
+// token bucket in l; a nil l lets every request through
+// Clients sending one of apiKeys in X-API-Key get a bucket of their
+// own, the rest share one per IP. Behind a reverse proxy, set
+// trustProxy to take the IP the proxy added to X-Forwarded-For
+func LimitRequests(l *ratelimit.Limiter, apiKeys []string, trustProxy bool) mux.MiddlewareFunc {
+	keys := make(map[string]bool, len(apiKeys))
+	for _, key := range apiKeys {
+		keys[key] = true
+	}
+	return func(next http.Handler) http.Handler {
+		if l == nil {
+			return next
+		}
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			ok, wait := l.Allow(clientKey(r, keys, trustProxy))
+			if ok {
+				next.ServeHTTP(w, r)
+				return
+			}
+			seconds := int(math.Ceil(wait.Seconds()))
+			setHeaders(w)
+			w.Header().Set("Retry-After", strconv.Itoa(seconds))
+			w.WriteHeader(http.StatusTooManyRequests)
+			json.NewEncoder(w).Encode(errorResponse{
+				Code:  "rate_limited",
+				Error: fmt.Sprintf("too many requests, retry in %ds", seconds),
+			})
+		})
+	}
+}
+
+// clientKey - bucket r counts against
+func clientKey(r *http.Request, apiKeys map[string]bool, trustProxy bool) string {
+	if key := r.Header.Get("X-API-Key"); key != "" && apiKeys[key] {
+		return "key:" + key
+	}
+	if trustProxy {
+		if ip := forwardedFor(r); ip != "" {
+			return "ip:" + ip
+		}
+	}
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		host = r.RemoteAddr
+	}
+	return "ip:" + host
+}
+
+// forwardedFor - the last address in X-Forwarded-For, the one added by
+// the proxy in front of us; those before it are whatever the client sent
+func forwardedFor(r *http.Request) string {
+	values := r.Header.Values("X-Forwarded-For")
+	if len(values) == 0 {
+		return ""
+	}
+	hops := strings.Split(values[len(values)-1], ",")
+	return strings.TrimSpace(hops[len(hops)-1])
+}
@@ -0,0 +1,63 @@
+package endpoints
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"net/http"
+	"time"
+
+	"github.com/gilmoreg/seibiki/internal/logging"
+	"github.com/gorilla/mux"
+	"go.uber.org/zap"
+)
+
+// requestIDHeader - header a request ID is read from and echoed in
+const requestIDHeader = "X-Request-ID"
+
+// LogRequests - router middleware giving each request an ID and logging it
+// once handled. The ID is taken from X-Request-ID if the client or a proxy
+// sent a usable one, and generated otherwise. It is echoed in the response
+// and carried in the request context, so repository and connector
+// log lines for the request include it
+func LogRequests(l *zap.SugaredLogger) mux.MiddlewareFunc {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			id := r.Header.Get(requestIDHeader)
+			if !validRequestID(id) {
+				id = newRequestID()
+			}
+			w.Header().Set(requestIDHeader, id)
+			ctx := logging.WithRequestID(r.Context(), id)
+
+			begin := time.Now()
+			rec := &statusRecorder{ResponseWriter: w, status: http.StatusOK}
+			next.ServeHTTP(rec, r.WithContext(ctx))
+			logging.FromContext(ctx, l).Infow("request",
+				"method", r.Method,
+				"path", r.URL.Path,
+				"status", rec.status,
+				"duration_ms", float64(time.Since(begin))/float64(time.Millisecond),
+			)
+		})
+	}
+}
+
+// validRequestID - true if id is short and only printable ASCII,
+// so a client cannot flood or forge log lines with it
+func validRequestID(id string) bool {
+	if id == "" || len(id) > 128 {
+		return false
+	}
+	for i := 0; i < len(id); i++ {
+		if id[i] <= ' ' || id[i] > '~' {
+			return false
+		}
+	}
+	return true
+}
+
+func newRequestID() string {
+	b := make([]byte, 16)
+	rand.Read(b)
+	return hex.EncodeToString(b)
+}
@@ -0,0 +1,103 @@
+package endpoints
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gilmoreg/seibiki/internal/dictionary"
+	"github.com/go-kit/kit/metrics"
+	"github.com/gorilla/mux"
+)
+
+// InstrumentRoutes - router middleware counting and timing requests,
+// labelled by route template and method, and counting them by status code
+func InstrumentRoutes(requests metrics.Counter, latency metrics.Histogram) mux.MiddlewareFunc {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			route := routeTemplate(r)
+			begin := time.Now()
+			rec := &statusRecorder{ResponseWriter: w, status: http.StatusOK}
+			next.ServeHTTP(rec, r)
+			requests.With("route", route, "method", r.Method, "code", strconv.Itoa(rec.status)).Add(1)
+			latency.With("route", route, "method", r.Method).Observe(time.Since(begin).Seconds())
+		})
+	}
+}
+
+// Instruments - lookup metrics recorded by the lookup endpoints,
+// which see the whole request; either may be nil
+type Instruments struct {
+	// Tokens - tokens in the text of each lookup request
+	Tokens metrics.Histogram
+	// Meanings - meanings the request's strictness kept or dropped,
+	// labelled by pos and outcome
+	Meanings metrics.Counter
+}
+
+// observeTokens - record the tokens in a lookup's text
+func (in Instruments) observeTokens(count int) {
+	if in.Tokens != nil {
+		in.Tokens.Observe(float64(count))
+	}
+}
+
+// countMeanings - count the meanings of each token in word that are
+// still in filtered, the same word after ApplyStrictness, as kept, and
+// the rest as dropped
+func (in Instruments) countMeanings(word, filtered dictionary.Word) {
+	if in.Meanings == nil {
+		return
+	}
+	for i, token := range word.Tokens {
+		before, after := countTokenMeanings(token), countTokenMeanings(filtered.Tokens[i])
+		pos := token.POSLabel()
+		if after > 0 {
+			in.Meanings.With("pos", pos, "outcome", "kept").Add(float64(after))
+		}
+		if before > after {
+			in.Meanings.With("pos", pos, "outcome", "dropped").Add(float64(before - after))
+		}
+	}
+}
+
+// countTokens - tokens in words
+func countTokens(words []dictionary.Word) int {
+	count := 0
+	for _, word := range words {
+		count += len(word.Tokens)
+	}
+	return count
+}
+
+// countTokenMeanings - meanings in every entry of token
+func countTokenMeanings(token dictionary.Token) int {
+	count := 0
+	for _, entry := range token.Entries {
+		count += len(entry.Meanings)
+	}
+	return count
+}
+
+// statusRecorder - remembers the status code written
+// Passes Flush through, so streamed lookups still stream, and unwraps
+// for http.ResponseController, so they can set their own write deadlines
+type statusRecorder struct {
+	http.ResponseWriter
+	status int
+}
+
+func (r *statusRecorder) WriteHeader(status int) {
+	r.status = status
+	r.ResponseWriter.WriteHeader(status)
+}
+
+func (r *statusRecorder) Flush() {
+	if f, ok := r.ResponseWriter.(http.Flusher); ok {
+		f.Flush()
+	}
+}
+
+func (r *statusRecorder) Unwrap() http.ResponseWriter {
+	return r.ResponseWriter
+}
@@ -0,0 +1,101 @@
+package endpoints
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/gilmoreg/seibiki/internal/dictionary"
+	"github.com/gilmoreg/seibiki/internal/service"
+)
+
+// streamWriteTimeout - how long each event may take to write
+// The server's WriteTimeout covers the whole response, which a long
+// document can take more than, so streams set a deadline per event instead
+const streamWriteTimeout = 30 * time.Second
+
+// StreamHandler - lookup that writes each Word as soon as its sentence
+// is resolved, in sentence order
+// Responds with Server-Sent Events ("word", then "error" or "done") if
+// the client accepts text/event-stream, otherwise with NDJSON where a
+// line with a "code" is an error. Errors found before the first Word
+// get the same plain JSON response as /api/lookup
+// The tokens of every sentence are recorded together in in, as one lookup
+func StreamHandler(svc service.LookupService, limits Limits, in Instruments) http.Handler {
+	decode := decodeQueryRequest(limits)
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ctx := r.Context()
+		req, err := decode(ctx, r)
+		if err != nil {
+			encodeError(ctx, err, w)
+			return
+		}
+		s := &streamWriter{
+			w:   w,
+			rc:  http.NewResponseController(w),
+			sse: strings.Contains(r.Header.Get("Accept"), "text/event-stream"),
+		}
+		query := req.(queryRequest)
+		count := 0
+		err = service.Stream(query.context(ctx), svc, query.Query, func(word dictionary.Word) error {
+			count += len(word.Tokens)
+			return s.write("word", query.decorate(word, in))
+		})
+		if _, partial := err.(service.PartialError); err == nil || partial {
+			in.observeTokens(count)
+		}
+		if err != nil && !s.started {
+			encodeError(ctx, err, w)
+			return
+		}
+		if err != nil {
+			_, res := errorStatus(err)
+			s.write("error", res)
+			return
+		}
+		if s.sse {
+			s.write("done", struct{}{})
+		}
+	})
+}
+
+// streamWriter - writes events as SSE or NDJSON, flushing each one
+type streamWriter struct {
+	w       http.ResponseWriter
+	rc      *http.ResponseController
+	sse     bool
+	started bool
+}
+
+func (s *streamWriter) write(event string, v interface{}) error {
+	b, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+	// Not supported by every ResponseWriter, e.g. httptest's
+	s.rc.SetWriteDeadline(time.Now().Add(streamWriteTimeout))
+	if !s.started {
+		setHeaders(s.w)
+		if s.sse {
+			s.w.Header().Set("Content-Type", "text/event-stream")
+		} else {
+			s.w.Header().Set("Content-Type", "application/x-ndjson")
+		}
+		s.w.Header().Set("Cache-Control", "no-cache")
+		// Stop nginx and the like buffering the whole response
+		s.w.Header().Set("X-Accel-Buffering", "no")
+		s.w.WriteHeader(http.StatusOK)
+		s.started = true
+	}
+	if s.sse {
+		_, err = fmt.Fprintf(s.w, "event: %s\ndata: %s\n\n", event, b)
+	} else {
+		_, err = fmt.Fprintf(s.w, "%s\n", b)
+	}
+	if f, ok := s.w.(http.Flusher); ok {
+		f.Flush()
+	}
+	return err
+}
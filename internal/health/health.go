@@ -0,0 +1,70 @@
+// Package health - readiness checks on the services seibiki depends on
+package health
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// Check - one dependency to probe
+type Check struct {
+	Name string
+	// Required - the service cannot answer lookups while this is down
+	// Optional dependencies, like the cache, are reported but do not fail readiness
+	Required bool
+	Ping     func(ctx context.Context) error
+}
+
+// Status - result of one Check
+type Status struct {
+	Up        bool    `json:"up"`
+	Required  bool    `json:"required"`
+	LatencyMS float64 `json:"latency_ms"`
+	Error     string  `json:"error,omitempty"`
+}
+
+// Report - results of every Check
+// Ready is false if any required Check failed
+type Report struct {
+	Ready  bool              `json:"ready"`
+	Checks map[string]Status `json:"checks"`
+}
+
+// Run - run checks concurrently, giving each at most timeout
+func Run(ctx context.Context, checks []Check, timeout time.Duration) Report {
+	report := Report{Ready: true, Checks: make(map[string]Status, len(checks))}
+	var mu sync.Mutex
+	var wg sync.WaitGroup
+	for _, check := range checks {
+		wg.Add(1)
+		go func(check Check) {
+			defer wg.Done()
+			status := run(ctx, check, timeout)
+			mu.Lock()
+			defer mu.Unlock()
+			report.Checks[check.Name] = status
+			if !status.Up && check.Required {
+				report.Ready = false
+			}
+		}(check)
+	}
+	wg.Wait()
+	return report
+}
+
+func run(ctx context.Context, check Check, timeout time.Duration) Status {
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+	start := time.Now()
+	err := check.Ping(ctx)
+	status := Status{
+		Up:        err == nil,
+		Required:  check.Required,
+		LatencyMS: float64(time.Since(start)) / float64(time.Millisecond),
+	}
+	if err != nil {
+		status.Error = err.Error()
+	}
+	return status
+}
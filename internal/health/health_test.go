@@ -0,0 +1,54 @@
+package health
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func up(ctx context.Context) error {
+	return nil
+}
+
+func down(ctx context.Context) error {
+	return errors.New("connection refused")
+}
+
+func hang(ctx context.Context) error {
+	<-ctx.Done()
+	return ctx.Err()
+}
+
+func TestRun(t *testing.T) {
+	t.Run("all up", func(t *testing.T) {
+		r := Run(context.Background(), []Check{
+			{Name: "mongodb", Required: true, Ping: up},
+			{Name: "redis", Ping: up},
+		}, time.Second)
+		assert.True(t, r.Ready)
+		assert.True(t, r.Checks["mongodb"].Up)
+		assert.True(t, r.Checks["redis"].Up)
+	})
+
+	t.Run("optional down", func(t *testing.T) {
+		r := Run(context.Background(), []Check{
+			{Name: "mongodb", Required: true, Ping: up},
+			{Name: "redis", Ping: down},
+		}, time.Second)
+		assert.True(t, r.Ready)
+		assert.False(t, r.Checks["redis"].Up)
+		assert.Equal(t, "connection refused", r.Checks["redis"].Error)
+	})
+
+	t.Run("required times out", func(t *testing.T) {
+		r := Run(context.Background(), []Check{
+			{Name: "mongodb", Required: true, Ping: hang},
+		}, 10*time.Millisecond)
+		assert.False(t, r.Ready)
+		assert.Equal(t, context.DeadlineExceeded.Error(), r.Checks["mongodb"].Error)
+		assert.True(t, r.Checks["mongodb"].LatencyMS >= 10)
+	})
+}
@@ -0,0 +1,229 @@
+// Package jmdict reads JMdict dictionary files
+// http://www.edrdg.org/jmdict/j_jmdict.html
+package jmdict
+
+import (
+	"compress/gzip"
+	"encoding/gob"
+	"encoding/json"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	"github.com/gilmoreg/seibiki/internal/dictionary"
+)
+
+// Load - read every entry from a JMdict file
+// The format is chosen by extension: .xml (upstream JMdict),
+// .json or .gob (a []dictionary.Entry export), optionally gzipped (.gz)
+func Load(path string) ([]dictionary.Entry, error) {
+	r, err := Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer r.Close()
+
+	var entries []dictionary.Entry
+	switch ext := format(path); ext {
+	case ".xml", "":
+		err = Parse(r, func(e dictionary.Entry) error {
+			entries = append(entries, e)
+			return nil
+		})
+	case ".json":
+		err = json.NewDecoder(r).Decode(&entries)
+	case ".gob":
+		err = gob.NewDecoder(r).Decode(&entries)
+	default:
+		err = fmt.Errorf("unsupported dictionary file format %q", ext)
+	}
+	return entries, err
+}
+
+// Open - open a dictionary file, decompressing it if it ends in .gz
+func Open(path string) (io.ReadCloser, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	if strings.ToLower(filepath.Ext(path)) != ".gz" {
+		return f, nil
+	}
+	gz, err := gzip.NewReader(f)
+	if err != nil {
+		f.Close()
+		return nil, err
+	}
+	return gzipFile{gz, f}, nil
+}
+
+type gzipFile struct {
+	*gzip.Reader
+	f *os.File
+}
+
+func (g gzipFile) Close() error {
+	g.Reader.Close()
+	return g.f.Close()
+}
+
+// format - extension of path, ignoring a trailing .gz
+func format(path string) string {
+	ext := strings.ToLower(filepath.Ext(path))
+	if ext == ".gz" {
+		ext = strings.ToLower(filepath.Ext(strings.TrimSuffix(path, filepath.Ext(path))))
+	}
+	return ext
+}
+
+// Save - write entries as a .json or .gob export readable by Load,
+// gzipped if path ends in .gz
+func Save(path string, entries []dictionary.Entry) error {
+	f, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	var w io.Writer = f
+	var gz *gzip.Writer
+	if strings.ToLower(filepath.Ext(path)) == ".gz" {
+		gz = gzip.NewWriter(f)
+		w = gz
+	}
+
+	switch ext := format(path); ext {
+	case ".json":
+		err = json.NewEncoder(w).Encode(entries)
+	case ".gob":
+		err = gob.NewEncoder(w).Encode(entries)
+	default:
+		return fmt.Errorf("unsupported export format %q", ext)
+	}
+	if gz != nil {
+		if closeErr := gz.Close(); err == nil {
+			err = closeErr
+		}
+	}
+	return err
+}
+
+// Parse - stream entries from upstream JMdict XML, calling fn for each
+// Part of speech and misc codes are kept in their entity form (e.g. "&n;")
+// to match the codes used by dictionary.Filter
+func Parse(r io.Reader, fn func(dictionary.Entry) error) error {
+	d := xml.NewDecoder(r)
+	d.Entity = make(map[string]string)
+	for {
+		tok, err := d.Token()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		switch t := tok.(type) {
+		case xml.Directive:
+			// The DTD declares the entities used for codes
+			for _, m := range entityDecl.FindAllSubmatch(t, -1) {
+				name := string(m[1])
+				d.Entity[name] = "&" + name + ";"
+			}
+		case xml.StartElement:
+			if t.Name.Local != "entry" {
+				continue
+			}
+			var e entry
+			if err := d.DecodeElement(&e, &t); err != nil {
+				return err
+			}
+			if err := fn(e.convert()); err != nil {
+				return err
+			}
+		}
+	}
+}
+
+var entityDecl = regexp.MustCompile(`<!ENTITY\s+(\S+)\s+"`)
+
+// entry - JMdict <entry> element
+type entry struct {
+	Sequence int       `xml:"ent_seq"`
+	Kanji    []kanji   `xml:"k_ele"`
+	Readings []reading `xml:"r_ele"`
+	Senses   []sense   `xml:"sense"`
+}
+
+type kanji struct {
+	Text     string   `xml:"keb"`
+	Priority []string `xml:"ke_pri"`
+}
+
+type reading struct {
+	Text     string   `xml:"reb"`
+	Priority []string `xml:"re_pri"`
+}
+
+type sense struct {
+	PartOfSpeech []string `xml:"pos"`
+	Misc         []string `xml:"misc"`
+	Gloss        []gloss  `xml:"gloss"`
+}
+
+type gloss struct {
+	Lang string `xml:"lang,attr"`
+	Text string `xml:",chardata"`
+}
+
+// convert - JMdict entry to dictionary.Entry
+// Each sense becomes one Meaning with its English glosses joined
+func (e entry) convert() dictionary.Entry {
+	result := dictionary.Entry{
+		Sequence: e.Sequence,
+		Meanings: make([]dictionary.Meaning, 0),
+	}
+	// Priority tags are kept per entry, not per form
+	seen := make(map[string]bool)
+	addPriority := func(tags []string) {
+		for _, tag := range tags {
+			if !seen[tag] {
+				seen[tag] = true
+				result.Priority = append(result.Priority, tag)
+			}
+		}
+	}
+	for _, k := range e.Kanji {
+		result.Kanji = append(result.Kanji, k.Text)
+		addPriority(k.Priority)
+	}
+	for _, r := range e.Readings {
+		result.Readings = append(result.Readings, r.Text)
+		addPriority(r.Priority)
+	}
+	// A sense without <pos> shares the part of speech of the one before it
+	var pos []string
+	for _, s := range e.Senses {
+		if len(s.PartOfSpeech) > 0 {
+			pos = s.PartOfSpeech
+		}
+		glosses := make([]string, 0)
+		for _, g := range s.Gloss {
+			if g.Lang == "" || g.Lang == "eng" {
+				glosses = append(glosses, g.Text)
+			}
+		}
+		if len(glosses) == 0 {
+			continue
+		}
+		result.Meanings = append(result.Meanings, dictionary.Meaning{
+			Gloss:        strings.Join(glosses, "; "),
+			PartOfSpeech: pos,
+			Misc:         s.Misc,
+		})
+	}
+	return result
+}
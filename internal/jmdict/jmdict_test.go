@@ -0,0 +1,81 @@
+package jmdict
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/gilmoreg/seibiki/internal/dictionary"
+	"github.com/stretchr/testify/assert"
+)
+
+const sample = "testdata/JMdict_sample.xml"
+
+func TestLoadXML(t *testing.T) {
+	entries, err := Load(sample)
+	assert.Nil(t, err)
+	assert.Equal(t, 4, len(entries))
+
+	samui := entries[0]
+	assert.Equal(t, 1216250, samui.Sequence)
+	assert.Equal(t, []string{"寒い"}, samui.Kanji)
+	assert.Equal(t, []string{"さむい"}, samui.Readings)
+	// priority tags of every form, once each
+	assert.Equal(t, []string{"ichi1", "news1"}, samui.Priority)
+	assert.Equal(t, 2, len(samui.Meanings))
+	// non-English glosses are dropped
+	assert.Equal(t, "cold (e.g. weather)", samui.Meanings[0].Gloss)
+	// pos carries over to following senses
+	assert.Equal(t, []string{"&adj-i;"}, samui.Meanings[1].PartOfSpeech)
+	assert.Equal(t, "uninteresting (esp. joke); lame", samui.Meanings[1].Gloss)
+
+	assert.Equal(t, []string{"&v5m;", "&vt;"}, entries[1].Meanings[0].PartOfSpeech)
+	assert.Equal(t, []string{"&uk;"}, entries[2].Meanings[0].Misc)
+	assert.Nil(t, entries[3].Kanji)
+}
+
+func TestLoadJSON(t *testing.T) {
+	dir, err := ioutil.TempDir("", "jmdict")
+	assert.Nil(t, err)
+	defer os.RemoveAll(dir)
+
+	path := filepath.Join(dir, "entries.json")
+	b, _ := json.Marshal([]dictionary.Entry{{Sequence: 1, Readings: []string{"ココア"}}})
+	assert.Nil(t, ioutil.WriteFile(path, b, 0644))
+
+	entries, err := Load(path)
+	assert.Nil(t, err)
+	assert.Equal(t, 1, entries[0].Sequence)
+}
+
+func TestSave(t *testing.T) {
+	dir, err := ioutil.TempDir("", "jmdict")
+	assert.Nil(t, err)
+	defer os.RemoveAll(dir)
+
+	entries, err := Load(sample)
+	assert.Nil(t, err)
+	for _, name := range []string{"entries.json", "entries.gob.gz"} {
+		t.Run(name, func(t *testing.T) {
+			path := filepath.Join(dir, name)
+			assert.Nil(t, Save(path, entries))
+			loaded, err := Load(path)
+			assert.Nil(t, err)
+			assert.Equal(t, entries, loaded)
+		})
+	}
+}
+
+func TestLoadErrors(t *testing.T) {
+	t.Run("missing file", func(t *testing.T) {
+		_, err := Load("testdata/nothing.xml")
+		assert.NotNil(t, err)
+	})
+
+	t.Run("unsupported format", func(t *testing.T) {
+		_, err := Load("jmdict.go")
+		assert.NotNil(t, err)
+	})
+}
@@ -0,0 +1,59 @@
+// Package logging - zap loggers, and the request ID carried in a context
+// so every log line written for a request can be tied back to it
+package logging
+
+import (
+	"context"
+	"fmt"
+
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+type contextKey int
+
+const requestIDKey contextKey = iota
+
+// New - production logger writing to stderr at level ("debug", "info",
+// "warn" or "error") as "json", or as "console" for reading in a terminal
+func New(level, format string) (*zap.SugaredLogger, error) {
+	var lvl zapcore.Level
+	if err := lvl.UnmarshalText([]byte(level)); err != nil {
+		return nil, fmt.Errorf("unknown log level %q", level)
+	}
+	c := zap.NewProductionConfig()
+	c.Level = zap.NewAtomicLevelAt(lvl)
+	c.EncoderConfig.TimeKey = "time"
+	c.EncoderConfig.EncodeTime = zapcore.ISO8601TimeEncoder
+	switch format {
+	case "json":
+	case "console":
+		c.Encoding = "console"
+	default:
+		return nil, fmt.Errorf("unknown log format %q", format)
+	}
+	l, err := c.Build()
+	if err != nil {
+		return nil, err
+	}
+	return l.Sugar(), nil
+}
+
+// WithRequestID - ctx carrying id
+func WithRequestID(ctx context.Context, id string) context.Context {
+	return context.WithValue(ctx, requestIDKey, id)
+}
+
+// RequestID - id carried by ctx, or "" if there is none
+func RequestID(ctx context.Context) string {
+	id, _ := ctx.Value(requestIDKey).(string)
+	return id
+}
+
+// FromContext - l, tagging every line with the request ID in ctx if there is one
+func FromContext(ctx context.Context, l *zap.SugaredLogger) *zap.SugaredLogger {
+	if id := RequestID(ctx); id != "" {
+		return l.With("request_id", id)
+	}
+	return l
+}
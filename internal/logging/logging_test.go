@@ -0,0 +1,35 @@
+package logging
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+	"go.uber.org/zap/zaptest/observer"
+)
+
+func TestNew(t *testing.T) {
+	l, err := New("warn", "json")
+	assert.Nil(t, err)
+	assert.False(t, l.Desugar().Core().Enabled(zapcore.InfoLevel))
+	assert.True(t, l.Desugar().Core().Enabled(zapcore.WarnLevel))
+
+	_, err = New("loud", "json")
+	assert.NotNil(t, err)
+	_, err = New("info", "xml")
+	assert.NotNil(t, err)
+}
+
+func TestFromContext(t *testing.T) {
+	core, logs := observer.New(zapcore.InfoLevel)
+	l := zap.New(core).Sugar()
+
+	FromContext(context.Background(), l).Info("no request")
+	FromContext(WithRequestID(context.Background(), "abc"), l).Info("request")
+
+	entries := logs.All()
+	assert.Empty(t, entries[0].ContextMap())
+	assert.Equal(t, "abc", entries[1].ContextMap()["request_id"])
+}
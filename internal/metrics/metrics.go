@@ -0,0 +1,104 @@
+// Package metrics - Prometheus series served on /metrics
+// Each is a go-kit metric, so the decorators recording them
+// do not depend on Prometheus
+package metrics
+
+import (
+	"github.com/go-kit/kit/metrics"
+	kitprometheus "github.com/go-kit/kit/metrics/prometheus"
+	stdprometheus "github.com/prometheus/client_golang/prometheus"
+)
+
+const namespace = "seibiki"
+
+// Metrics - every series the server records
+type Metrics struct {
+	// Requests - HTTP requests by route, method and status code
+	Requests metrics.Counter
+	// RequestLatency - HTTP request seconds by route and method
+	RequestLatency metrics.Histogram
+	// Lookups - lookups by whether they failed
+	Lookups metrics.Counter
+	// LookupLatency - lookup seconds by whether they failed
+	LookupLatency metrics.Histogram
+	// Tokens - tokens in the text of each lookup request,
+	// streamed or not
+	Tokens metrics.Histogram
+	// Meanings - meanings by the token's IPADIC or UniDic part of speech
+	// ("other" if the filter has no mapping for it), and whether the
+	// request's strictness kept them ("kept") or dropped them ("dropped")
+	Meanings metrics.Counter
+	// Cache - cache reads by result: hit, miss or error
+	Cache metrics.Counter
+	// DBLatency - MongoDB query seconds by method and whether they failed
+	DBLatency metrics.Histogram
+	// DictionaryLatency - dictionary Repository lookup seconds, cache
+	// and db together, by method and whether they failed
+	DictionaryLatency metrics.Histogram
+}
+
+// New - Metrics registered with the default Prometheus registry
+// Call once; registering a series twice panics
+func New() Metrics {
+	return Metrics{
+		Requests: kitprometheus.NewCounterFrom(stdprometheus.CounterOpts{
+			Namespace: namespace,
+			Subsystem: "http",
+			Name:      "requests_total",
+			Help:      "HTTP requests by route, method and status code.",
+		}, []string{"route", "method", "code"}),
+		RequestLatency: kitprometheus.NewHistogramFrom(stdprometheus.HistogramOpts{
+			Namespace: namespace,
+			Subsystem: "http",
+			Name:      "request_duration_seconds",
+			Help:      "HTTP request latency by route and method.",
+			Buckets:   stdprometheus.DefBuckets,
+		}, []string{"route", "method"}),
+		Lookups: kitprometheus.NewCounterFrom(stdprometheus.CounterOpts{
+			Namespace: namespace,
+			Subsystem: "lookup",
+			Name:      "requests_total",
+			Help:      "Lookups by whether they failed.",
+		}, []string{"error"}),
+		LookupLatency: kitprometheus.NewHistogramFrom(stdprometheus.HistogramOpts{
+			Namespace: namespace,
+			Subsystem: "lookup",
+			Name:      "duration_seconds",
+			Help:      "Lookup latency by whether they failed.",
+			Buckets:   stdprometheus.DefBuckets,
+		}, []string{"error"}),
+		Tokens: kitprometheus.NewHistogramFrom(stdprometheus.HistogramOpts{
+			Namespace: namespace,
+			Subsystem: "lookup",
+			Name:      "tokens",
+			Help:      "Tokens in the text of each lookup request.",
+			Buckets:   stdprometheus.ExponentialBuckets(1, 2, 12),
+		}, []string{}),
+		Meanings: kitprometheus.NewCounterFrom(stdprometheus.CounterOpts{
+			Namespace: namespace,
+			Subsystem: "filter",
+			Name:      "meanings_total",
+			Help:      "Meanings kept or dropped by the strictness of each lookup request, by token part of speech.",
+		}, []string{"pos", "outcome"}),
+		Cache: kitprometheus.NewCounterFrom(stdprometheus.CounterOpts{
+			Namespace: namespace,
+			Subsystem: "cache",
+			Name:      "reads_total",
+			Help:      "Cache reads by result: hit, miss or error.",
+		}, []string{"result"}),
+		DBLatency: kitprometheus.NewHistogramFrom(stdprometheus.HistogramOpts{
+			Namespace: namespace,
+			Subsystem: "mongodb",
+			Name:      "query_duration_seconds",
+			Help:      "MongoDB query latency by method and whether they failed.",
+			Buckets:   stdprometheus.DefBuckets,
+		}, []string{"method", "error"}),
+		DictionaryLatency: kitprometheus.NewHistogramFrom(stdprometheus.HistogramOpts{
+			Namespace: namespace,
+			Subsystem: "dictionary",
+			Name:      "lookup_duration_seconds",
+			Help:      "Dictionary lookup latency, cache and db together, by method and whether they failed.",
+			Buckets:   stdprometheus.DefBuckets,
+		}, []string{"method", "error"}),
+	}
+}
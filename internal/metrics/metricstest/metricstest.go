@@ -0,0 +1,92 @@
+// Package metricstest - go-kit generic metrics for tests, read back by label values
+package metricstest
+
+import (
+	"strings"
+	"sync"
+	"sync/atomic"
+
+	"github.com/go-kit/kit/metrics"
+	"github.com/go-kit/kit/metrics/generic"
+)
+
+// Counter - metrics.Counter adding to a generic.Counter for each set of label values
+// generic.Counter's own With returns a copy, so its labelled totals cannot be read
+type Counter struct {
+	lvs      []string
+	counters *sync.Map
+}
+
+// NewCounter - empty Counter
+func NewCounter() *Counter {
+	return &Counter{counters: &sync.Map{}}
+}
+
+// With - Counter adding to the total for c's label values and lvs
+func (c *Counter) With(lvs ...string) metrics.Counter {
+	return &Counter{lvs: append(append([]string{}, c.lvs...), lvs...), counters: c.counters}
+}
+
+// Add - add delta to the total for c's label values
+func (c *Counter) Add(delta float64) {
+	counter, _ := c.counters.LoadOrStore(key(c.lvs), generic.NewCounter(""))
+	counter.(*generic.Counter).Add(delta)
+}
+
+// Value - total added with label values lvs
+func (c *Counter) Value(lvs ...string) float64 {
+	if counter, ok := c.counters.Load(key(lvs)); ok {
+		return counter.(*generic.Counter).Value()
+	}
+	return 0
+}
+
+// Histogram - metrics.Histogram observing into a generic.Histogram for each
+// set of label values, and counting the observations
+type Histogram struct {
+	lvs        []string
+	histograms *sync.Map
+}
+
+type histogram struct {
+	*generic.Histogram
+	count uint64
+}
+
+// NewHistogram - empty Histogram
+func NewHistogram() *Histogram {
+	return &Histogram{histograms: &sync.Map{}}
+}
+
+// With - Histogram observing for h's label values and lvs
+func (h *Histogram) With(lvs ...string) metrics.Histogram {
+	return &Histogram{lvs: append(append([]string{}, h.lvs...), lvs...), histograms: h.histograms}
+}
+
+// Observe - observe value for h's label values
+func (h *Histogram) Observe(value float64) {
+	v, _ := h.histograms.LoadOrStore(key(h.lvs), &histogram{Histogram: generic.NewHistogram("", 50)})
+	hist := v.(*histogram)
+	hist.Observe(value)
+	atomic.AddUint64(&hist.count, 1)
+}
+
+// Count - observations made with label values lvs
+func (h *Histogram) Count(lvs ...string) int {
+	if v, ok := h.histograms.Load(key(lvs)); ok {
+		return int(atomic.LoadUint64(&v.(*histogram).count))
+	}
+	return 0
+}
+
+// Quantile - quantile q of the observations made with label values lvs
+func (h *Histogram) Quantile(q float64, lvs ...string) float64 {
+	if v, ok := h.histograms.Load(key(lvs)); ok {
+		return v.(*histogram).Quantile(q)
+	}
+	return 0
+}
+
+func key(lvs []string) string {
+	return strings.Join(lvs, "\x00")
+}
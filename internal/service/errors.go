@@ -0,0 +1,44 @@
+package service
+
+import (
+	"github.com/gilmoreg/seibiki/internal/dictionary"
+)
+
+// BadInputError - the query cannot be looked up as given
+type BadInputError struct {
+	Reason string
+}
+
+func (e BadInputError) Error() string {
+	return "bad input: " + e.Reason
+}
+
+// TooLargeError - the request is over a configured size limit
+type TooLargeError struct {
+	Reason string
+}
+
+func (e TooLargeError) Error() string {
+	return "too large: " + e.Reason
+}
+
+// UnavailableError - the dictionary backend (cache or database) failed
+// and nothing could be looked up
+type UnavailableError struct {
+	Err error
+}
+
+func (e UnavailableError) Error() string {
+	return "backend unavailable: " + e.Err.Error()
+}
+
+// PartialError - some lookups failed
+// Words holds everything that was resolved before the failure
+type PartialError struct {
+	Words []dictionary.Word
+	Err   error
+}
+
+func (e PartialError) Error() string {
+	return "partial result: " + e.Err.Error()
+}
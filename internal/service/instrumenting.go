@@ -0,0 +1,43 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/gilmoreg/seibiki/internal/dictionary"
+	"github.com/go-kit/kit/metrics"
+)
+
+// Middleware - decorates a LookupService
+type Middleware func(LookupService) LookupService
+
+type instrumentingMiddleware struct {
+	lookups metrics.Counter
+	latency metrics.Histogram
+	next    LookupService
+}
+
+// InstrumentingMiddleware - count and time lookups, labelled by whether they failed
+// Streamed lookups go through once per sentence; tokens per request and
+// the meanings each request's strictness kept or dropped are recorded
+// by the endpoints
+func InstrumentingMiddleware(lookups metrics.Counter, latency metrics.Histogram) Middleware {
+	return func(next LookupService) LookupService {
+		return instrumentingMiddleware{
+			lookups: lookups,
+			latency: latency,
+			next:    next,
+		}
+	}
+}
+
+func (mw instrumentingMiddleware) Lookup(ctx context.Context, query string) (words []dictionary.Word, err error) {
+	defer func(begin time.Time) {
+		lvs := []string{"error", fmt.Sprint(err != nil)}
+		mw.lookups.With(lvs...).Add(1)
+		mw.latency.With(lvs...).Observe(time.Since(begin).Seconds())
+	}(time.Now())
+
+	return mw.next.Lookup(ctx, query)
+}
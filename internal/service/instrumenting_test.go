@@ -0,0 +1,36 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/gilmoreg/seibiki/internal/dictionary"
+	"github.com/gilmoreg/seibiki/internal/metrics/metricstest"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestInstrumentingMiddleware(t *testing.T) {
+	words := []dictionary.Word{{Surface: "飲む"}}
+	lookups, latency := metricstest.NewCounter(), metricstest.NewHistogram()
+	svc := InstrumentingMiddleware(lookups, latency)(fixedService{words: words})
+
+	_, err := svc.Lookup(context.Background(), "飲む")
+	assert.Nil(t, err)
+	assert.Equal(t, 1.0, lookups.Value("error", "false"))
+	assert.Equal(t, 1, latency.Count("error", "false"))
+
+	svc = InstrumentingMiddleware(lookups, latency)(fixedService{err: errors.New("mongo down")})
+	_, err = svc.Lookup(context.Background(), "飲む")
+	assert.NotNil(t, err)
+	assert.Equal(t, 1.0, lookups.Value("error", "true"))
+}
+
+type fixedService struct {
+	words []dictionary.Word
+	err   error
+}
+
+func (s fixedService) Lookup(ctx context.Context, query string) ([]dictionary.Word, error) {
+	return s.words, s.err
+}
@@ -1,34 +1,136 @@
 package service
 
 import (
+	"context"
+	"strings"
+	"unicode/utf8"
+
 	"github.com/gilmoreg/seibiki/internal/dictionary"
+	"github.com/gilmoreg/seibiki/internal/logging"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
 	"go.uber.org/zap"
 )
 
+var tracer = otel.Tracer("github.com/gilmoreg/seibiki/internal/service")
+
 // LookupService - interface for kagome service
 type LookupService interface {
-	Lookup(query string) []dictionary.Word
+	Lookup(ctx context.Context, query string) ([]dictionary.Word, error)
 }
 
 type lookupService struct {
-	logger *zap.SugaredLogger
-	repo   dictionary.Repository
+	logger    *zap.SugaredLogger
+	repo      dictionary.Repository
+	tokenizer *dictionary.Tokenizer
 }
 
 // New returns a lookupService
-func New(logger *zap.SugaredLogger, repo dictionary.Repository) LookupService {
+func New(logger *zap.SugaredLogger, repo dictionary.Repository, tokenizer *dictionary.Tokenizer) LookupService {
 	return &lookupService{
-		logger: logger,
-		repo:   repo,
+		logger:    logger,
+		repo:      repo,
+		tokenizer: tokenizer,
+	}
+}
+
+type tokenizeOptionsKey struct{}
+
+// WithTokenizeOptions - ctx carrying the tokenizer dictionary and mode
+// a request asked for, used by every Lookup made with it
+func WithTokenizeOptions(ctx context.Context, o dictionary.TokenizeOptions) context.Context {
+	return context.WithValue(ctx, tokenizeOptionsKey{}, o)
+}
+
+// tokenize - split query into words with the options in ctx,
+// recording a span with the number of tokens
+func (s *lookupService) tokenize(ctx context.Context, query string) ([]dictionary.Word, error) {
+	_, span := tracer.Start(ctx, "dictionary.Tokenize")
+	defer span.End()
+	o, _ := ctx.Value(tokenizeOptionsKey{}).(dictionary.TokenizeOptions)
+	o = s.tokenizer.Options(o)
+	span.SetAttributes(attribute.String("sysdic", string(o.SysDic)), attribute.String("mode", string(o.Mode)))
+	words, err := s.tokenizer.Tokenize(query, o)
+	if err != nil {
+		return nil, BadInputError{Reason: err.Error()}
+	}
+	tokens := 0
+	for _, word := range words {
+		tokens += len(word.Tokens)
 	}
+	span.SetAttributes(attribute.Int("words", len(words)), attribute.Int("tokens", tokens))
+	return words, nil
 }
 
 // Lookup - tokenize and lookup tokens in dictionary
-func (s *lookupService) Lookup(query string) []dictionary.Word {
-	words := dictionary.Tokenize(query)
+// Tokens without entries are not an error; a failing backend is
+func (s *lookupService) Lookup(ctx context.Context, query string) ([]dictionary.Word, error) {
+	if err := validate(query); err != nil {
+		return nil, err
+	}
+	words, err := s.tokenize(ctx, query)
+	if err != nil {
+		return nil, err
+	}
+	entries, err := s.repo.LookupMany(ctx, dictionary.Bases(words))
+	found := len(entries) > 0
+	result := make([]dictionary.Word, 0)
+	for _, word := range words {
+		result = append(result, word.SetEntries(entries))
+	}
+	if err == nil {
+		var deinflected bool
+		result, deinflected, err = s.deinflect(ctx, result)
+		found = found || deinflected
+	}
+	if err == nil {
+		result, err = s.phrases(ctx, result)
+	}
+	if err != nil {
+		logging.FromContext(ctx, s.logger).Error(err)
+		if ctxErr := ctx.Err(); ctxErr != nil {
+			return nil, ctxErr
+		}
+		if found {
+			return result, PartialError{Words: result, Err: err}
+		}
+		return nil, UnavailableError{Err: err}
+	}
+	return result, nil
+}
+
+// validate - BadInputError if query cannot be looked up
+func validate(query string) error {
+	if !utf8.ValidString(query) {
+		return BadInputError{Reason: "query is not valid UTF-8"}
+	}
+	if strings.TrimSpace(query) == "" {
+		return BadInputError{Reason: "query is empty"}
+	}
+	return nil
+}
+
+// deinflect - retry tokens without entries under their possible dictionary forms
+// Reports whether any entries came back
+func (s *lookupService) deinflect(ctx context.Context, words []dictionary.Word) ([]dictionary.Word, bool, error) {
+	candidates := dictionary.DeinflectionCandidates(words)
+	if len(candidates) == 0 {
+		return words, false, nil
+	}
+	entries, err := s.repo.LookupMany(ctx, candidates)
 	result := make([]dictionary.Word, 0)
 	for _, word := range words {
-		result = append(result, word.GetEntries(s.repo))
+		result = append(result, word.SetDeinflectedEntries(entries))
+	}
+	return result, len(entries) > 0, err
+}
+
+// phrases - join adjacent words that form a longer dictionary entry
+func (s *lookupService) phrases(ctx context.Context, words []dictionary.Word) ([]dictionary.Word, error) {
+	candidates := dictionary.PhraseCandidates(words)
+	if len(candidates) == 0 {
+		return words, nil
 	}
-	return result
+	entries, err := s.repo.LookupMany(ctx, candidates)
+	return dictionary.JoinPhrases(words, entries), err
 }
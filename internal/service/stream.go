@@ -0,0 +1,111 @@
+package service
+
+import (
+	"context"
+	"strings"
+	"unicode"
+
+	"github.com/gilmoreg/seibiki/internal/dictionary"
+)
+
+// streamWorkers - sentences looked up at the same time by Stream
+const streamWorkers = 4
+
+// Stream - look up query one sentence at a time, calling emit with each
+// Word in sentence order as soon as its sentence is resolved
+// Bad input is reported before anything is emitted. A sentence with a
+// partial result is emitted and the rest still looked up; the
+// PartialError returned at the end then carries no Words
+func Stream(ctx context.Context, svc LookupService, query string, emit func(dictionary.Word) error) error {
+	if err := validate(query); err != nil {
+		return err
+	}
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	type result struct {
+		words []dictionary.Word
+		err   error
+	}
+	sentences := Sentences(query)
+	results := make([]chan result, len(sentences))
+	for i := range results {
+		// Buffered so workers never block on a consumer that has given up
+		results[i] = make(chan result, 1)
+	}
+	go func() {
+		sem := make(chan struct{}, streamWorkers)
+		for i, sentence := range sentences {
+			select {
+			case sem <- struct{}{}:
+			case <-ctx.Done():
+				return
+			}
+			go func(i int, sentence string) {
+				words, err := svc.Lookup(ctx, sentence)
+				results[i] <- result{words, err}
+				<-sem
+			}(i, sentence)
+		}
+	}()
+
+	var partial error
+	for i := range sentences {
+		var r result
+		select {
+		case r = <-results[i]:
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+		words := r.words
+		if p, ok := r.err.(PartialError); ok {
+			words, partial = p.Words, PartialError{Err: p.Err}
+		} else if r.err != nil {
+			return r.err
+		}
+		for _, word := range words {
+			if err := emit(word); err != nil {
+				return err
+			}
+		}
+	}
+	return partial
+}
+
+// Sentences - split text after each sentence-ending mark, keeping the
+// marks, closing brackets and whitespace that follow with their sentence
+// Blank text between sentences is never returned on its own
+func Sentences(text string) []string {
+	sentences := make([]string, 0)
+	runes := []rune(text)
+	start := 0
+	for i := 0; i < len(runes); i++ {
+		if !isSentenceEnd(runes[i]) {
+			continue
+		}
+		for i+1 < len(runes) && (isSentenceEnd(runes[i+1]) || isClosing(runes[i+1]) || unicode.IsSpace(runes[i+1])) {
+			i++
+		}
+		sentence := string(runes[start : i+1])
+		if strings.TrimSpace(sentence) == "" {
+			// Leading blank lines go with the next sentence
+			continue
+		}
+		sentences = append(sentences, sentence)
+		start = i + 1
+	}
+	if rest := string(runes[start:]); strings.TrimSpace(rest) != "" {
+		sentences = append(sentences, rest)
+	} else if len(sentences) > 0 {
+		sentences[len(sentences)-1] += rest
+	}
+	return sentences
+}
+
+func isSentenceEnd(r rune) bool {
+	return strings.ContainsRune("。！？!?\n", r)
+}
+
+func isClosing(r rune) bool {
+	return strings.ContainsRune("」』）)】", r)
+}
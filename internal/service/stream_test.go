@@ -0,0 +1,73 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/gilmoreg/seibiki/internal/dictionary"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSentences(t *testing.T) {
+	assert.Equal(t, []string{"寒い。", "「飲む？」\n", "ココア"}, Sentences("寒い。「飲む？」\nココア"))
+	assert.Equal(t, []string{"\n\n寒い！！ "}, Sentences("\n\n寒い！！ "))
+	assert.Equal(t, []string{"寒い"}, Sentences("寒い"))
+}
+
+func TestStream(t *testing.T) {
+	t.Run("sentence order", func(t *testing.T) {
+		// Earlier sentences resolve last
+		svc := &echoService{delay: map[string]time.Duration{"一。": 20 * time.Millisecond}}
+		surfaces := make([]string, 0)
+		err := Stream(context.Background(), svc, "一。二。三", func(w dictionary.Word) error {
+			surfaces = append(surfaces, w.Surface)
+			return nil
+		})
+		assert.Nil(t, err)
+		assert.Equal(t, []string{"一。", "二。", "三"}, surfaces)
+	})
+
+	t.Run("bad input", func(t *testing.T) {
+		err := Stream(context.Background(), &echoService{}, " ", func(dictionary.Word) error {
+			t.Fatal("nothing should be emitted")
+			return nil
+		})
+		assert.IsType(t, BadInputError{}, err)
+	})
+
+	t.Run("partial result carries on", func(t *testing.T) {
+		svc := &echoService{err: map[string]error{"二。": errors.New("mongo down")}}
+		count := 0
+		err := Stream(context.Background(), svc, "一。二。三", func(dictionary.Word) error {
+			count++
+			return nil
+		})
+		assert.IsType(t, PartialError{}, err)
+		assert.Equal(t, 3, count)
+	})
+
+	t.Run("emit error stops", func(t *testing.T) {
+		stop := errors.New("client gone")
+		err := Stream(context.Background(), &echoService{}, "一。二。三", func(dictionary.Word) error {
+			return stop
+		})
+		assert.Equal(t, stop, err)
+	})
+}
+
+// echoService - returns each query as a single Word
+type echoService struct {
+	delay map[string]time.Duration
+	err   map[string]error
+}
+
+func (e *echoService) Lookup(ctx context.Context, query string) ([]dictionary.Word, error) {
+	time.Sleep(e.delay[query])
+	words := []dictionary.Word{{Surface: query}}
+	if err := e.err[query]; err != nil {
+		return words, PartialError{Words: words, Err: err}
+	}
+	return words, nil
+}
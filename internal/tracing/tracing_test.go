@@ -0,0 +1,21 @@
+package tracing
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSetup(t *testing.T) {
+	shutdown, err := Setup(context.Background(), "stdout", "")
+	assert.Nil(t, err)
+	assert.Nil(t, shutdown(context.Background()))
+
+	shutdown, err = Setup(context.Background(), "none", "")
+	assert.Nil(t, err)
+	assert.Nil(t, shutdown(context.Background()))
+
+	_, err = Setup(context.Background(), "zipkin", "")
+	assert.NotNil(t, err)
+}
@@ -0,0 +1,259 @@
+// Package transliterate converts kana to hiragana and to romaji
+//
+// Kana alone do not say where a vowel is long: おう is one long vowel in
+// 東京 (tōkyō) but two in 思う (omou). ToRomaji only lengthens vowels
+// spelt with ー, as pronunciations are (トーキョー); ConvertReading also
+// uses the pronunciation to find the long vowels in a reading.
+package transliterate
+
+import (
+	"strings"
+)
+
+// System - a writing system kana can be converted to
+type System string
+
+const (
+	Hiragana System = "hiragana"
+	Hepburn  System = "hepburn"
+	Kunrei   System = "kunrei"
+	Nihon    System = "nihon"
+)
+
+// Systems - every supported System
+var Systems = []System{Hiragana, Hepburn, Kunrei, Nihon}
+
+// Valid - true if s is a supported System
+func (s System) Valid() bool {
+	for _, system := range Systems {
+		if s == system {
+			return true
+		}
+	}
+	return false
+}
+
+// Convert - kana in text written in system
+// Anything that is not kana is left as is
+func Convert(text string, system System) string {
+	if system == Hiragana {
+		return ToHiragana(text)
+	}
+	return ToRomaji(text, system)
+}
+
+// ConvertReading - reading written in system, with the long vowels of
+// pron marked in romaji: トウキョウ, pronounced トーキョー, is tōkyō
+// Only おう, おお and うう are lengthened; えい and いい are written
+// as spelt, as modified Hepburn does (センセイ → sensei)
+func ConvertReading(reading, pron string, system System) string {
+	if system == Hiragana {
+		return ToHiragana(reading)
+	}
+	return ToRomaji(markLongVowels(reading, pron), system)
+}
+
+// markLongVowels - reading with ー for each う or お that pron has as ー
+// after a kana ending in the same vowel (おう, おお, うう)
+// reading is returned as is if it does not line up with pron
+func markLongVowels(reading, pron string) string {
+	r, p := []rune(ToHiragana(reading)), []rune(ToHiragana(pron))
+	if len(r) != len(p) {
+		return reading
+	}
+	for i := 1; i < len(r); i++ {
+		if p[i] != 'ー' {
+			continue
+		}
+		switch vowel(r[i-1]) {
+		case 'o':
+			if r[i] == 'う' || r[i] == 'お' {
+				r[i] = 'ー'
+			}
+		case 'u':
+			if r[i] == 'う' {
+				r[i] = 'ー'
+			}
+		}
+	}
+	return string(r)
+}
+
+// vowel - the vowel the Hepburn romaji of kana ends in, or 0
+func vowel(kana rune) byte {
+	roma := tables[Hepburn][string(kana)]
+	if roma == "" {
+		return 0
+	}
+	return roma[len(roma)-1]
+}
+
+// ToHiragana - katakana in text converted to hiragana
+func ToHiragana(text string) string {
+	return strings.Map(func(r rune) rune {
+		if r >= 'ァ' && r <= 'ヶ' {
+			return r - 'ァ' + 'ぁ'
+		}
+		return r
+	}, text)
+}
+
+// ToRomaji - kana in text converted to Hepburn, Kunrei or Nihon-shiki romaji
+// っ doubles the following consonant (Hepburn っち is tchi), or is written '
+// with no consonant to double, as at the end of あっ; ん before a
+// vowel or y is written n', and ー lengthens the vowel before it with a
+// macron (Hepburn) or circumflex (Kunrei and Nihon-shiki)
+func ToRomaji(text string, system System) string {
+	table := tables[system]
+	if table == nil {
+		table = tables[Hepburn]
+	}
+	runes := []rune(ToHiragana(text))
+	var b strings.Builder
+	geminate := false
+	// unpaired - write a っ that has no consonant after it to double
+	unpaired := func() {
+		if geminate {
+			b.WriteString("'")
+			geminate = false
+		}
+	}
+	for i := 0; i < len(runes); {
+		r := runes[i]
+		switch {
+		case r == 'っ':
+			unpaired()
+			geminate = true
+			i++
+			continue
+		case r == 'ー':
+			unpaired()
+			lengthen(&b, system)
+			i++
+			continue
+		case r == 'ん':
+			unpaired()
+			b.WriteString("n")
+			if i+1 < len(runes) && strings.ContainsRune("あいうえおやゆよ", runes[i+1]) {
+				b.WriteString("'")
+			}
+			i++
+			continue
+		}
+
+		// Two kana first, for ゃ, ゅ, ょ and the like
+		roma, n := "", 0
+		if i+1 < len(runes) {
+			roma, n = table[string(runes[i:i+2])], 2
+		}
+		if roma == "" {
+			roma, n = table[string(r)], 1
+		}
+		if roma == "" {
+			unpaired()
+			b.WriteRune(r)
+			i++
+			continue
+		}
+		if geminate {
+			if system == Hepburn && strings.HasPrefix(roma, "ch") {
+				b.WriteString("t")
+			} else if !strings.ContainsRune("aiueo", rune(roma[0])) {
+				b.WriteByte(roma[0])
+			} else {
+				b.WriteString("'")
+			}
+			geminate = false
+		}
+		b.WriteString(roma)
+		i += n
+	}
+	unpaired()
+	return b.String()
+}
+
+// lengthen - replace the last vowel written with its long form
+func lengthen(b *strings.Builder, system System) {
+	s := b.String()
+	if s == "" {
+		return
+	}
+	long := map[byte]string{'a': "â", 'i': "î", 'u': "û", 'e': "ê", 'o': "ô"}
+	if system == Hepburn {
+		long = map[byte]string{'a': "ā", 'i': "ī", 'u': "ū", 'e': "ē", 'o': "ō"}
+	}
+	if l, ok := long[s[len(s)-1]]; ok {
+		b.Reset()
+		b.WriteString(s[:len(s)-1] + l)
+	}
+}
+
+var tables = map[System]map[string]string{
+	Hepburn: buildTable(nil),
+	Kunrei: buildTable(map[string]string{
+		"し": "si", "じ": "zi", "ち": "ti", "ぢ": "zi", "つ": "tu", "づ": "zu", "ふ": "hu",
+	}),
+	Nihon: buildTable(map[string]string{
+		"し": "si", "じ": "zi", "ち": "ti", "ぢ": "di", "つ": "tu", "づ": "du", "ふ": "hu",
+		"ゐ": "wi", "ゑ": "we", "を": "wo",
+	}),
+}
+
+// buildTable - kana to romaji, Hepburn unless overridden
+func buildTable(overrides map[string]string) map[string]string {
+	table := make(map[string]string)
+	rows := []struct {
+		kana string
+		roma []string
+	}{
+		{"あいうえお", []string{"a", "i", "u", "e", "o"}},
+		{"かきくけこ", []string{"ka", "ki", "ku", "ke", "ko"}},
+		{"がぎぐげご", []string{"ga", "gi", "gu", "ge", "go"}},
+		{"さしすせそ", []string{"sa", "shi", "su", "se", "so"}},
+		{"ざじずぜぞ", []string{"za", "ji", "zu", "ze", "zo"}},
+		{"たちつてと", []string{"ta", "chi", "tsu", "te", "to"}},
+		{"だぢづでど", []string{"da", "ji", "zu", "de", "do"}},
+		{"なにぬねの", []string{"na", "ni", "nu", "ne", "no"}},
+		{"はひふへほ", []string{"ha", "hi", "fu", "he", "ho"}},
+		{"ばびぶべぼ", []string{"ba", "bi", "bu", "be", "bo"}},
+		{"ぱぴぷぺぽ", []string{"pa", "pi", "pu", "pe", "po"}},
+		{"まみむめも", []string{"ma", "mi", "mu", "me", "mo"}},
+		{"やゆよ", []string{"ya", "yu", "yo"}},
+		{"らりるれろ", []string{"ra", "ri", "ru", "re", "ro"}},
+		{"わゐゑを", []string{"wa", "i", "e", "o"}},
+		{"ぁぃぅぇぉ", []string{"a", "i", "u", "e", "o"}},
+		{"ゃゅょゎ", []string{"ya", "yu", "yo", "wa"}},
+		{"ゔ", []string{"vu"}},
+	}
+	for _, row := range rows {
+		for i, kana := range []rune(row.kana) {
+			table[string(kana)] = row.roma[i]
+		}
+	}
+	for kana, roma := range overrides {
+		table[kana] = roma
+	}
+
+	// Contracted sounds: き+ゃ → kya, し+ゃ → sha (Hepburn) or sya
+	for _, kana := range "きぎしじちぢにひびぴみり" {
+		stem := strings.TrimSuffix(table[string(kana)], "i")
+		if stem != "j" && (len(stem) < 2 || stem[len(stem)-1] != 'h') {
+			stem += "y"
+		}
+		for small, vowel := range map[string]string{"ゃ": "a", "ゅ": "u", "ぇ": "e", "ょ": "o"} {
+			table[string(kana)+small] = stem + vowel
+		}
+	}
+
+	// Sounds found in loanwords
+	for kana, roma := range map[string]string{
+		"ふぁ": "fa", "ふぃ": "fi", "ふぇ": "fe", "ふぉ": "fo",
+		"てぃ": "ti", "でぃ": "di", "とぅ": "tu", "どぅ": "du",
+		"うぃ": "wi", "うぇ": "we", "うぉ": "wo",
+		"ゔぁ": "va", "ゔぃ": "vi", "ゔぇ": "ve", "ゔぉ": "vo",
+		"つぁ": "tsa", "つぃ": "tsi", "つぇ": "tse", "つぉ": "tso",
+	} {
+		table[kana] = roma
+	}
+	return table
+}
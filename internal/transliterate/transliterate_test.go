@@ -0,0 +1,82 @@
+package transliterate
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestToHiragana(t *testing.T) {
+	assert.Equal(t, "とーきょー", ToHiragana("トーキョー"))
+	assert.Equal(t, "ゔぁいおりん abc", ToHiragana("ヴァイオリン abc"))
+}
+
+func TestToRomaji(t *testing.T) {
+	tests := []struct {
+		kana                   string
+		hepburn, kunrei, nihon string
+	}{
+		{"シンブン", "shinbun", "sinbun", "sinbun"},
+		{"チズ", "chizu", "tizu", "tizu"},
+		{"ハナヂ", "hanaji", "hanazi", "hanadi"},
+		{"ツヅク", "tsuzuku", "tuzuku", "tuduku"},
+		{"フジ", "fuji", "huzi", "huzi"},
+		{"キョウ", "kyou", "kyou", "kyou"},
+		{"シャシン", "shashin", "syasin", "syasin"},
+		{"チョット", "chotto", "tyotto", "tyotto"},
+		// っ before ch
+		{"マッチャ", "matcha", "mattya", "mattya"},
+		// っ with no consonant to double
+		{"アッ", "a'", "a'", "a'"},
+		{"ヤッ!", "ya'!", "ya'!", "ya'!"},
+		{"エッエ", "e'e", "e'e", "e'e"},
+		// long vowels marked with ー
+		{"トーキョー", "tōkyō", "tôkyô", "tôkyô"},
+		// ん before a vowel or y
+		{"キンエン", "kin'en", "kin'en", "kin'en"},
+		{"コンヤ", "kon'ya", "kon'ya", "kon'ya"},
+		{"ヲ", "o", "o", "wo"},
+		{"パーティー", "pātī", "pâtî", "pâtî"},
+		{"寒いネ", "寒ine", "寒ine", "寒ine"},
+	}
+	for _, test := range tests {
+		t.Run(test.kana, func(t *testing.T) {
+			assert.Equal(t, test.hepburn, ToRomaji(test.kana, Hepburn))
+			assert.Equal(t, test.kunrei, ToRomaji(test.kana, Kunrei))
+			assert.Equal(t, test.nihon, ToRomaji(test.kana, Nihon))
+		})
+	}
+}
+
+func TestConvertReading(t *testing.T) {
+	tests := []struct {
+		name, reading, pron string
+		hepburn, kunrei     string
+	}{
+		{"東京", "トウキョウ", "トーキョー", "tōkyō", "tôkyô"},
+		{"勉強", "ベンキョウ", "ベンキョー", "benkyō", "benkyô"},
+		{"大きい", "オオキイ", "オーキイ", "ōkii", "ôkii"},
+		{"空気", "クウキ", "クーキ", "kūki", "kûki"},
+		// not long: う is the verb ending
+		{"思う", "オモウ", "オモウ", "omou", "omou"},
+		// えい is written as spelt
+		{"先生", "センセイ", "センセー", "sensei", "sensei"},
+		// pron that does not line up is ignored
+		{"は", "ハ", "ワ", "ha", "ha"},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			assert.Equal(t, test.hepburn, ConvertReading(test.reading, test.pron, Hepburn))
+			assert.Equal(t, test.kunrei, ConvertReading(test.reading, test.pron, Kunrei))
+		})
+	}
+	// hiragana readings keep their spelling
+	assert.Equal(t, "とうきょう", ConvertReading("トウキョウ", "トーキョー", Hiragana))
+}
+
+func TestConvert(t *testing.T) {
+	assert.Equal(t, "さむい", Convert("サムイ", Hiragana))
+	assert.Equal(t, "samui", Convert("サムイ", Hepburn))
+	assert.True(t, Kunrei.Valid())
+	assert.False(t, System("wapuro").Valid())
+}
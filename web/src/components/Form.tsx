@@ -1,6 +1,6 @@
 import * as React from "react";
 import './Form.css'
-import { WordData } from 'src/types';
+import { ErrorData, WordData } from 'src/types';
 
 const apiPort = process.env.NODE_ENV === 'production' ? '' : ':3001';
 
@@ -34,8 +34,17 @@ class Form extends React.Component<FormProps, FormState> {
             method: 'POST',
             body: JSON.stringify(this.state),
         })
-            .then(res => res.json())
-            .then(res => this.props.update(res))
+            .then(res => res.json().then(body => ({ status: res.status, body })))
+            .then(({ status, body }) => {
+                if (status === 200) {
+                    this.props.update(body as WordData[]);
+                } else if (status === 207) {
+                    // Partial result - show what we have
+                    this.props.update((body as ErrorData).words || []);
+                } else {
+                    console.error((body as ErrorData).error);
+                }
+            })
             .catch(err => console.error(err))
     }
 
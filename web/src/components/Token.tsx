@@ -24,6 +24,8 @@ class Token extends React.Component<TokenProps, any> {
                 <ul>
                     <li>Part of Speech: {pos}</li>
                     <li>Base: {token.base}</li>
+                    {token.deinflected &&
+                        <li>Deinflected: {token.deinflected} ({(token.inflection || []).join(' → ')})</li>}
                     <li>Reading: {token.reading}</li>
                     <li>Proununciation: {token.pron}</li>
                 </ul>
@@ -1,5 +1,6 @@
 import * as React from 'react';
 import { WordData } from '../types';
+import Entry from './Entry';
 import Token from './Token';
 import './Word.css';
 
@@ -15,10 +16,16 @@ class Word extends React.Component<WordProps, any> {
     render() {
         const { word } = this.props;
         const tokens = word.tokens && word.tokens.map((t, i) => <Token token={t} key={t.id + i} />);
+        const entries = word.entries && word.entries.map((e, i) => <Entry entry={e} key={e.sequence + i} />);
 
         return (
             <div className="Word">
                 <h1>{word.surface}</h1>
+                {word.base && word.base !== word.surface && <p>{word.base}</p>}
+                {entries &&
+                    <div className="entries">
+                        {entries}
+                    </div>}
                 <h2>Parts</h2>
                 <div className="tokens">
                     {tokens}
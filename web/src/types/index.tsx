@@ -2,6 +2,12 @@ export interface MeaningData {
     gloss: string;
     partofspeech: string[];
     misc: string[];
+    match?: MatchData; // whether the meaning fits the token's part of speech
+}
+
+export interface MatchData {
+    matched: boolean;
+    reason: string;
 }
 
 export interface EntryData {
@@ -9,6 +15,8 @@ export interface EntryData {
     kanji: string[] | null;
     readings: string[];
     meanings: MeaningData[] | null;
+    priority?: string[]; // JMdict priority tags, e.g. ichi1, news1, nf12
+    score?: number; // how likely the entry is meant, when ranked
 }
 
 export interface TokenData {
@@ -20,11 +28,45 @@ export interface TokenData {
     reading: string;
     pron: string;
     entries: EntryData[] | null;
+    deinflected?: string; // dictionary form entries were found under, when base had none
+    inflection?: string[]; // inflections undone to reach it, outermost first
+    furigana?: RubyData[]; // only when requested with furigana: true
+    transliterations?: { [system: string]: TransliterationData }; // requested with transliterate
+}
+
+export interface TransliterationData {
+    reading: string;
+    pron: string;
+}
+
+export interface RubyData {
+    text: string;
+    reading?: string; // hiragana over a kanji run, absent for kana
 }
 
 export interface WordData {
     surface: string;
     tokens: TokenData[];
+    base?: string; // dictionary form of a phrase joined from several words
+    entries?: EntryData[]; // entries for the phrase as a whole
+}
+
+export interface FuriganaData {
+    words: WordData[];
+    html: string; // the query with <ruby> markup
+}
+
+export interface SearchResultData {
+    entries: EntryData[];
+    total: number; // entries matching across every page
+    offset: number;
+    limit: number;
+}
+
+export interface ErrorData {
+    code: string; // bad_input, backend_unavailable, partial_result, timeout, cancelled, internal
+    error: string;
+    words?: WordData[];
 }
 
 export interface StoreState {